@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Источники проекта задачи для учета переключения слотов
+const (
+	AffinityProject = "project" // проект Jira по ключу задачи
+	AffinityParent  = "parent"  // строка-родитель в структуре, например эпик
+)
+
+// parseAffinityConfig возвращает время на переключение слота и допустимое ожидание слота своего проекта
+func parseAffinityConfig(cfg AffinityConfig) (time.Duration, time.Duration, error) {
+	if !cfg.Enabled {
+		return 0, 0, nil
+	}
+	var switchCost, maxWait time.Duration
+	var err error
+	if cfg.SwitchCost != "" {
+		if switchCost, err = parseGanttDuration(cfg.SwitchCost); err != nil {
+			return 0, 0, fmt.Errorf("некорректный switch_cost: %w", err)
+		}
+	}
+	if cfg.MaxWait != "" {
+		if maxWait, err = parseGanttDuration(cfg.MaxWait); err != nil {
+			return 0, 0, fmt.Errorf("некорректный max_wait: %w", err)
+		}
+	}
+	return switchCost, maxWait, nil
+}
+
+func isAffinityFieldKey(key string) bool {
+	return key != "" && key != AffinityProject && key != AffinityParent
+}
+
+// resolveContexts выставляет задачам проект, по которому учитывается переключение слотов
+func resolveContexts(tasks []*LevelingTask, cfg AffinityConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	contexts := make(map[string]bool)
+	for _, task := range tasks {
+		task.Context = taskContext(task, cfg.Key)
+		if task.Context != "" {
+			contexts[task.Context] = true
+		}
+	}
+	log.Printf("Проектов для учета переключения слотов: %d\n", len(contexts))
+}
+
+func taskContext(task *LevelingTask, key string) string {
+	switch key {
+	case "", AffinityProject:
+		project, _, _ := strings.Cut(task.Issue.Key, "-")
+		return project
+	case AffinityParent:
+		if task.Row.Parent == 0 {
+			return ""
+		}
+		return "row:" + strconv.Itoa(task.Row.Parent)
+	default:
+		return task.Issue.FieldString(key)
+	}
+}
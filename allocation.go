@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func validateAllocationConfig(cfg AllocationConfig) error {
+	for label, rule := range cfg.Labels {
+		if rule.Slots < 0 {
+			return fmt.Errorf("метка '%s': отрицательное количество слотов", label)
+		}
+		if rule.Fraction < 0 || rule.Fraction > 1 {
+			return fmt.Errorf("метка '%s': доля загрузки должна быть от 0 до 1", label)
+		}
+	}
+	return nil
+}
+
+// resolveAllocation выставляет задачам количество слотов и долю загрузки слота из полей или по меткам
+func resolveAllocation(tasks []*LevelingTask, cfg AllocationConfig) {
+	if cfg.SlotsField == "" && cfg.FractionField == "" && len(cfg.Labels) == 0 {
+		return
+	}
+
+	var count int
+	for _, task := range tasks {
+		issue := task.Issue
+		for _, label := range issue.Labels() {
+			if rule, ok := cfg.Labels[label]; ok {
+				task.Width, task.Fraction = rule.Slots, rule.Fraction
+				break
+			}
+		}
+
+		if cfg.SlotsField != "" {
+			if n, ok := issue.FieldNumber(cfg.SlotsField); ok {
+				if n >= 1 {
+					task.Width = int(n)
+				} else {
+					log.Printf("[WARNING] Задача %s: некорректное количество слотов %g\n", issue.Key, n)
+				}
+			}
+		}
+		if cfg.FractionField != "" {
+			if v := issue.FieldString(cfg.FractionField); v != "" {
+				if f, ok := parseProgress(v); ok && f > 0 {
+					task.Fraction = f
+				} else {
+					log.Printf("[WARNING] Задача %s: некорректная доля загрузки '%s'\n", issue.Key, v)
+				}
+			}
+		}
+
+		if task.Width > 1 || (task.Fraction > 0 && task.Fraction < 1) {
+			count++
+			log.Printf("Задача %s: слотов %d, загрузка %.0f%%\n", issue.Key, max(task.Width, 1), allocationPercent(task.Fraction))
+		}
+	}
+	log.Printf("Задач с особой загрузкой слотов: %d\n", count)
+}
+
+func allocationPercent(fraction float64) float64 {
+	if fraction <= 0 || fraction >= 1 {
+		return 100
+	}
+	return fraction * 100
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// parseBuffersConfig возвращает фиксированные буферы после задачи и в конце проекта
+func parseBuffersConfig(cfg BuffersConfig) (time.Duration, time.Duration, error) {
+	if cfg.AfterTaskPercent < 0 || cfg.ProjectPercent < 0 {
+		return 0, 0, fmt.Errorf("отрицательный процент буфера")
+	}
+	var afterTask, project time.Duration
+	var err error
+	if cfg.AfterTask != "" {
+		if afterTask, err = parseGanttDuration(cfg.AfterTask); err != nil {
+			return 0, 0, fmt.Errorf("некорректный буфер after_task: %w", err)
+		}
+	}
+	if cfg.Project != "" {
+		if project, err = parseGanttDuration(cfg.Project); err != nil {
+			return 0, 0, fmt.Errorf("некорректный буфер project: %w", err)
+		}
+	}
+	return afterTask, project, nil
+}
+
+// resolveBuffers выставляет задачам буфер, в течение которого слот остается занят после окончания задачи
+func resolveBuffers(tasks []*LevelingTask, cfg BuffersConfig, afterTask time.Duration) {
+	if afterTask <= 0 && cfg.AfterTaskPercent <= 0 {
+		return
+	}
+	for _, task := range tasks {
+		if task.Summary {
+			continue
+		}
+		task.Buffer = afterTask + time.Duration(float64(task.Duration)*cfg.AfterTaskPercent/100)
+	}
+}
+
+// reportProjectBuffer выводит окончание плана и окончание с буфером проекта. Буфер проекта задается
+// фиксированным временем и процентом от длины плана от даты начала выравнивания до окончания последней задачи.
+func reportProjectBuffer(tasks []*LevelingTask, timeline Timeline, startDateId int, cfg BuffersConfig, project time.Duration) {
+	if project <= 0 && cfg.ProjectPercent <= 0 {
+		return
+	}
+	var finish time.Duration
+	for _, task := range tasks {
+		if !task.Summary && task.PlacementErr == nil {
+			finish = max(finish, task.Finish)
+		}
+	}
+	start := max(0, timeline.OffsetForDate(startDateId))
+	if finish <= start {
+		return
+	}
+
+	buffer := project + time.Duration(float64(finish-start)*cfg.ProjectPercent/100)
+	finishDateId, err := timeline.FinishDateForOffset(finish)
+	if err != nil {
+		log.Printf("[WARNING] Не удалось определить дату окончания плана: %v\n", err)
+		return
+	}
+	bufferedDateId, err := timeline.FinishDateForOffset(finish + buffer)
+	if err != nil {
+		log.Printf("[WARNING] Не удалось определить дату окончания с буфером проекта: %v\n", err)
+		return
+	}
+	log.Printf("Окончание плана: %d, буфер проекта %s, окончание с буфером: %d\n", finishDateId, buffer, bufferedDateId)
+}
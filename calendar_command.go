@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var weekdayNames = []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+
+// runCalendarCommand выводит календарь, который инструмент использует при выравнивании структуры
+func runCalendarCommand(args []string) {
+	fs := flag.NewFlagSet("calendar", flag.ExitOnError)
+	config := fs.String("c", "config.yml", "Путь к конфигурационному файлу YAML (если не указано — config.yml)")
+	structure := fs.String("s", "", "Название секции из 'structures', календарь которой нужно вывести")
+	from := fs.Int("from", 0, "Начало интервала для вывода особых дней, dateId (если не указано — дата начала диаграммы)")
+	to := fs.Int("to", 0, "Конец интервала для вывода особых дней включительно, dateId (если не указано — год от начала выравнивания)")
+	_ = fs.Parse(args)
+
+	if *structure == "" {
+		fmt.Println("Usage: calendar -s <structure>")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(*config)
+	if err != nil {
+		log.Fatalf("Не удалось загрузить кнфигурационный файл: %v", err)
+	}
+	structureCfg, ok := cfg.Structures[*structure]
+	if !ok {
+		log.Fatalf("В спикке структур нет настроек для '%s' в конфигурационном файле", *structure)
+	}
+
+	client := NewJiraClient(cfg.Client)
+	ganttID, gantt, overrides, err := loadGantt(client, structureCfg)
+	if err != nil {
+		log.Fatalf("Не удалось получить календарь структуры '%s': %v", *structure, err)
+	}
+
+	startId, startDelay := levelingStart(structureCfg, gantt)
+	fromId, toId := *from, *to
+	if fromId <= 0 {
+		fromId = gantt.StartDateId
+	}
+	if toId <= 0 {
+		t, err := parseDateId(startId)
+		if err != nil {
+			log.Fatalf("Некорректная дата начала выравнивания %d: %v", startId, err)
+		}
+		toId = dateIdFromTime(t.AddDate(1, 0, 0))
+	}
+
+	printCalendar(os.Stdout, structureCfg, ganttID, gantt, overrides, fromId, toId, startId, startDelay)
+}
+
+func printCalendar(w io.Writer, structure StructureConfig, ganttID int, gantt *GanttMeta, overrides []CalendarOverride, fromId, toId, startId int, startDelay time.Duration) {
+	cal := &gantt.Calendar
+
+	fmt.Fprintf(w, "Структура: %d, диаграмма Ганта: %d\n", structure.ID, ganttID)
+	fmt.Fprintf(w, "Календарь: %s (calendarId %d), часовой пояс: %s\n", cal.Name, cal.ID, gantt.ZoneId)
+	fmt.Fprintf(w, "Дата начала диаграммы: %d\n", gantt.StartDateId)
+
+	fmt.Fprintln(w, "\nРабочее время по дням недели:")
+	var week time.Duration
+	for i, day := range cal.WeekDays {
+		name := fmt.Sprintf("%d", i)
+		if i < len(weekdayNames) {
+			name = weekdayNames[i]
+		}
+		fmt.Fprintf(w, "  %s  %-8s %s\n", name, day.Duration, formatTimeRanges(day.TimeRanges))
+		week += day.Duration
+	}
+	fmt.Fprintf(w, "Рабочих часов в неделю: %s\n", week)
+
+	bySource := make(map[int]CalendarOverride, len(overrides))
+	for _, o := range overrides {
+		bySource[o.DateId] = o
+	}
+	var days []int
+	for dateId := range cal.CustomDays {
+		if dateId >= fromId && dateId <= toId {
+			days = append(days, dateId)
+		}
+	}
+	sort.Ints(days)
+
+	fmt.Fprintf(w, "\nОсобые дни с %d по %d: %d\n", fromId, toId, len(days))
+	for _, dateId := range days {
+		day := cal.CustomDays[dateId]
+		weekday := ""
+		if t, err := parseDateId(dateId); err == nil {
+			weekday = weekdayNames[mondayBasedWeekday(t)]
+		}
+		source := "Gantt"
+		if o, ok := bySource[dateId]; ok {
+			source = strings.TrimSpace(fmt.Sprintf("%s, было %s %s", o.Source, o.Before, o.Comment))
+		}
+		fmt.Fprintf(w, "  %d %s  %-8s %-24s (%s)\n", dateId, weekday, day.Duration, formatTimeRanges(day.TimeRanges), source)
+	}
+
+	fmt.Fprintf(w, "\nДата начала выравнивания: %d\n", startId)
+	fmt.Fprintf(w, "Начальная задержка слотов (рабочее время от %d до %d): %s\n", gantt.StartDateId, startId, startDelay)
+}
+
+func formatTimeRanges(ranges []TimeRange) string {
+	if len(ranges) == 0 {
+		return "выходной"
+	}
+	parts := make([]string, 0, len(ranges))
+	for _, tr := range ranges {
+		parts = append(parts, fmt.Sprintf("%s-%s", formatTimeId(tr.StartTimeId), formatTimeId(tr.FinishTimeId)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatTimeId(id int) string {
+	return fmt.Sprintf("%02d:%02d", id/10000, (id/100)%100)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CalendarOverride описывает один переопределенный день календаря
+type CalendarOverride struct {
+	DateId   int
+	Before   time.Duration
+	After    time.Duration
+	Source   string
+	Comment  string
+	Schedule DaySchedule
+}
+
+// applyCalendarOverrides накладывает дни из конфигурации и ics-файла поверх календаря Ганта.
+// Дни из конфигурации имеют приоритет над днями из ics-файла.
+func applyCalendarOverrides(cal *Calendar, cfg CalendarConfig) ([]CalendarOverride, error) {
+	var overrides []CalendarOverride
+
+	if err := validateOverrideHours(cfg.ICSHours); err != nil {
+		return nil, fmt.Errorf("ics_hours: %w", err)
+	}
+	if cfg.ICSFile != "" {
+		events, err := loadICSFile(cfg.ICSFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения ics-файла %s: %w", cfg.ICSFile, err)
+		}
+		hours := time.Duration(cfg.ICSHours * float64(time.Hour))
+		for _, event := range events {
+			for _, dateId := range dateIdsBetween(event.StartDateId, event.FinishDateId) {
+				o, err := newCalendarOverride(cal, dateId, hours, "ics", event.Summary)
+				if err != nil {
+					return nil, err
+				}
+				overrides = append(overrides, o)
+			}
+		}
+	}
+
+	for _, day := range cfg.Days {
+		if day.DateId <= 0 {
+			return nil, fmt.Errorf("не указан date_id для переопределения календаря")
+		}
+		finishDateId := day.ToDateId
+		if finishDateId <= 0 {
+			finishDateId = day.DateId
+		}
+		if finishDateId < day.DateId {
+			return nil, fmt.Errorf("to_date_id %d меньше date_id %d", finishDateId, day.DateId)
+		}
+		next, err := nextDateId(finishDateId)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateOverrideHours(day.Hours); err != nil {
+			return nil, fmt.Errorf("переопределение календаря с %d: %w", day.DateId, err)
+		}
+		hours := time.Duration(day.Hours * float64(time.Hour))
+		for _, dateId := range dateIdsBetween(day.DateId, next) {
+			o, err := newCalendarOverride(cal, dateId, hours, "config", day.Comment)
+			if err != nil {
+				return nil, err
+			}
+			overrides = append(overrides, o)
+		}
+	}
+
+	// Последнее переопределение дня побеждает, поэтому в отчет попадает только оно
+	byDate := make(map[int]CalendarOverride)
+	for _, o := range overrides {
+		if prev, ok := byDate[o.DateId]; ok {
+			o.Before = prev.Before
+		}
+		byDate[o.DateId] = o
+	}
+
+	if cal.CustomDays == nil {
+		cal.CustomDays = make(map[int]DaySchedule)
+	}
+	result := make([]CalendarOverride, 0, len(byDate))
+	for dateId, o := range byDate {
+		cal.CustomDays[dateId] = o.Schedule
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DateId < result[j].DateId })
+
+	return result, nil
+}
+
+// validateOverrideHours проверяет, что количество рабочих часов помещается в сутки
+func validateOverrideHours(hours float64) error {
+	if hours < 0 || hours > 24 {
+		return fmt.Errorf("количество рабочих часов %v вне интервала от 0 до 24", hours)
+	}
+	return nil
+}
+
+func newCalendarOverride(cal *Calendar, dateId int, hours time.Duration, source, comment string) (CalendarOverride, error) {
+	schedule, err := shortenDaySchedule(cal.GetScheduleForDate(dateId), hours)
+	if err != nil {
+		return CalendarOverride{}, fmt.Errorf("переопределение дня %d: %w", dateId, err)
+	}
+	return CalendarOverride{
+		DateId:   dateId,
+		Before:   cal.GetWorkingDurationForDate(dateId),
+		After:    schedule.Duration,
+		Source:   source,
+		Comment:  comment,
+		Schedule: schedule,
+	}, nil
+}
+
+// shortenDaySchedule обрезает расписание дня так, чтобы рабочее время не превышало d.
+// Если в исходном дне нет рабочих интервалов, а d больше нуля, день считается рабочим с 09:00,
+// а если d не помещается до конца суток — так, чтобы рабочее время заканчивалось в 24:00.
+// Рабочее время дня с рабочими интервалами увеличить нельзя.
+func shortenDaySchedule(base DaySchedule, d time.Duration) (DaySchedule, error) {
+	if d <= 0 {
+		return DaySchedule{}, nil
+	}
+	if d > 24*time.Hour {
+		return DaySchedule{}, fmt.Errorf("рабочее время %s не помещается в сутки", d)
+	}
+	if len(base.TimeRanges) == 0 {
+		start := min(9*time.Hour, 24*time.Hour-d)
+		return DaySchedule{
+			TimeRanges: []TimeRange{{StartTimeId: timeIdFromDuration(start), FinishTimeId: timeIdFromDuration(start + d)}},
+			Duration:   d,
+		}, nil
+	}
+
+	var result DaySchedule
+	left := d
+	for _, tr := range base.TimeRanges {
+		if left <= 0 {
+			break
+		}
+		start := parseTimeId(tr.StartTimeId)
+		finish := parseTimeId(tr.FinishTimeId)
+		if finish-start > left {
+			finish = start + left
+		}
+		result.TimeRanges = append(result.TimeRanges, TimeRange{
+			StartTimeId:  tr.StartTimeId,
+			FinishTimeId: timeIdFromDuration(finish),
+		})
+		result.Duration += finish - start
+		left -= finish - start
+	}
+	if left > 0 {
+		return DaySchedule{}, fmt.Errorf("в рабочем дне %s, увеличить его до %s нельзя", result.Duration, d)
+	}
+	return result, nil
+}
+
+func logCalendarOverrides(overrides []CalendarOverride) {
+	if len(overrides) == 0 {
+		return
+	}
+	log.Printf("Переопределено дней календаря: %d\n", len(overrides))
+	for _, o := range overrides {
+		log.Printf("  %d: %s -> %s (%s) %s\n", o.DateId, o.Before, o.After, o.Source, o.Comment)
+	}
+}
+
+type icsEvent struct {
+	StartDateId  int
+	FinishDateId int // не включительно
+	Summary      string
+}
+
+// loadICSFile читает события из iCalendar-файла. Поддерживаются только разовые события,
+// повторяющиеся события (RRULE) пропускаются с предупреждением.
+func loadICSFile(path string) ([]icsEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Разворачиваем перенесенные строки (RFC 5545, 3.1)
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []icsEvent
+	var current *icsEvent
+	var recurring bool
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			current = &icsEvent{}
+			recurring = false
+			continue
+		case "END:VEVENT":
+			if current == nil {
+				continue
+			}
+			switch {
+			case recurring:
+				log.Printf("[WARNING] Повторяющееся событие '%s' в ics-файле не поддерживается и будет пропущено\n", current.Summary)
+			case current.StartDateId == 0:
+				log.Printf("[WARNING] Событие '%s' в ics-файле без DTSTART будет пропущено\n", current.Summary)
+			default:
+				if current.FinishDateId <= current.StartDateId {
+					next, err := nextDateId(current.StartDateId)
+					if err != nil {
+						return nil, err
+					}
+					current.FinishDateId = next
+				}
+				events = append(events, *current)
+			}
+			current = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "DTSTART":
+			dateId, err := parseICSDate(value)
+			if err != nil {
+				return nil, err
+			}
+			current.StartDateId = dateId
+		case "DTEND":
+			dateId, err := parseICSDate(value)
+			if err != nil {
+				return nil, err
+			}
+			current.FinishDateId = dateId
+		case "SUMMARY":
+			current.Summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ").Replace(value)
+		case "RRULE", "RDATE":
+			recurring = true
+		}
+	}
+
+	return events, nil
+}
+
+// parseICSDate принимает значения вида 20250101 и 20250101T000000Z
+func parseICSDate(value string) (int, error) {
+	if len(value) < 8 {
+		return 0, fmt.Errorf("некорректная дата в ics-файле: %s", value)
+	}
+	dateId, err := parseInt(value[:8])
+	if err != nil {
+		return 0, fmt.Errorf("некорректная дата в ics-файле: %s", value)
+	}
+	if _, err := parseDateId(dateId); err != nil {
+		return 0, fmt.Errorf("некорректная дата в ics-файле: %s", value)
+	}
+	return dateId, nil
+}
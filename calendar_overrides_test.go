@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShortenDaySchedule(t *testing.T) {
+	workday := DaySchedule{
+		TimeRanges: []TimeRange{{StartTimeId: 90000, FinishTimeId: 130000}, {StartTimeId: 140000, FinishTimeId: 180000}},
+		Duration:   8 * time.Hour,
+	}
+	tests := []struct {
+		name    string
+		base    DaySchedule
+		hours   time.Duration
+		want    []TimeRange
+		wantErr bool
+	}{
+		{name: "выходной", base: workday, hours: 0},
+		{name: "сокращенный день", base: workday, hours: 6 * time.Hour,
+			want: []TimeRange{{StartTimeId: 90000, FinishTimeId: 130000}, {StartTimeId: 140000, FinishTimeId: 160000}}},
+		{name: "тот же день", base: workday, hours: 8 * time.Hour, want: workday.TimeRanges},
+		{name: "удлинение рабочего дня", base: workday, hours: 10 * time.Hour, wantErr: true},
+		{name: "рабочий выходной", hours: 8 * time.Hour, want: []TimeRange{{StartTimeId: 90000, FinishTimeId: 170000}}},
+		{name: "длинный рабочий выходной", hours: 20 * time.Hour, want: []TimeRange{{StartTimeId: 40000, FinishTimeId: 240000}}},
+		{name: "больше суток", hours: 25 * time.Hour, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shortenDaySchedule(tt.base, tt.hours)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка: %v, ожидается ошибка: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got.TimeRanges, tt.want) {
+				t.Errorf("интервалы %v, ожидается %v", got.TimeRanges, tt.want)
+			}
+			if got.Duration != tt.hours {
+				t.Errorf("рабочее время %s, ожидается %s", got.Duration, tt.hours)
+			}
+		})
+	}
+}
+
+func TestValidateOverrideHours(t *testing.T) {
+	for _, hours := range []float64{-1, 24.5} {
+		if err := validateOverrideHours(hours); err == nil {
+			t.Errorf("часы %v должны отклоняться", hours)
+		}
+	}
+	for _, hours := range []float64{0, 7.5, 24} {
+		if err := validateOverrideHours(hours); err != nil {
+			t.Errorf("часы %v: %v", hours, err)
+		}
+	}
+}
+
+func TestLoadICSFile(t *testing.T) {
+	content := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20250101\r\n" +
+		"DTEND;VALUE=DATE:20250103\r\n" +
+		"SUMMARY:Новогодние\r\n" +
+		"  каникулы\\, часть 1\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20250308T000000Z\r\n" +
+		"SUMMARY:Один день\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20250101\r\n" +
+		"RRULE:FREQ=YEARLY\r\n" +
+		"SUMMARY:Повторяющееся\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Без даты\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	path := filepath.Join(t.TempDir(), "holidays.ics")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := loadICSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []icsEvent{
+		{StartDateId: 20250101, FinishDateId: 20250103, Summary: "Новогодние каникулы, часть 1"},
+		{StartDateId: 20250308, FinishDateId: 20250309, Summary: "Один день"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("события %+v, ожидается %+v", events, want)
+	}
+}
+
+func TestLoadICSFileInvalidDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.ics")
+	content := "BEGIN:VEVENT\nDTSTART:2025\nEND:VEVENT\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadICSFile(path); err == nil {
+		t.Error("некорректная дата должна возвращать ошибку")
+	}
+}
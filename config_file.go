@@ -21,11 +21,227 @@ type StructureConfig struct {
 	JQL              string `yaml:"jql"`
 	ParallelProjects int    `yaml:"parallel_projects"`
 	StartDateID      int    `yaml:"start_date_id"`
+	Duplicates       string `yaml:"duplicates"` // first, all или fail
+
+	Source string             `yaml:"source"` // jql (по умолчанию) или forest
+	Forest ForestSourceConfig `yaml:"forest"`
+	Sort   []SortKeyConfig    `yaml:"sort"`
+	Fields []string           `yaml:"fields"` // дополнительные поля Jira по ID или имени
+
+	Durations  DurationsConfig   `yaml:"durations"`
+	Allocation AllocationConfig  `yaml:"allocation"`
+	Affinity   AffinityConfig    `yaml:"affinity"`
+	WIPLimits  []WIPLimitConfig  `yaml:"wip_limits"`
+	Stability  StabilityConfig   `yaml:"stability"`
+	Buffers    BuffersConfig     `yaml:"buffers"`
+	Horizon    HorizonConfig     `yaml:"horizon"`
+	Milestones MilestonesConfig  `yaml:"milestones"`
+	Rules      []IssueRuleConfig `yaml:"rules"`
+	Progress   ProgressConfig    `yaml:"progress"`
+	Deadlines  DeadlinesConfig   `yaml:"deadlines"`
+	Strategy   string            `yaml:"strategy"` // greedy (по умолчанию), anneal или зарегистрированная через RegisterStrategy
+	Anneal     AnnealConfig      `yaml:"anneal"`
+	Calendar   CalendarConfig    `yaml:"calendar"`
+	Hierarchy  HierarchyConfig   `yaml:"hierarchy"`
+
+	Resources     []ResourceConfig `yaml:"resources"`
+	ResourcesFile string           `yaml:"resources_file"` // CSV: resource,date_id,to_date_id,hours,comment
+
+	Pools                    []PoolConfig           `yaml:"pools"`
+	SharedWeight             float64                `yaml:"shared_weight"`              // доля структуры в общих пулах, по умолчанию 1
+	ParallelProjectsSchedule []CapacityPeriodConfig `yaml:"parallel_projects_schedule"` // слоты пула default по датам
+}
+
+// DurationsConfig задает цепочку источников длительности задачи
+type DurationsConfig struct {
+	Sources           []string          `yaml:"sources"` // по умолчанию только gantt
+	StoryPointsField  string            `yaml:"story_points_field"`
+	HoursPerPoint     float64           `yaml:"hours_per_point"`
+	IssueTypeDefaults map[string]string `yaml:"issue_type_defaults"` // длительность в формате Gantt: 2d 4h
+	Default           string            `yaml:"default"`             // для типов, не указанных в issue_type_defaults
+}
+
+// AllocationConfig задает задачи, которые занимают несколько слотов одновременно или часть слота
+type AllocationConfig struct {
+	SlotsField    string                          `yaml:"slots_field"`    // числовое поле с количеством слотов
+	FractionField string                          `yaml:"fraction_field"` // поле с долей загрузки: 0.5, 50 или 50%
+	Labels        map[string]AllocationRuleConfig `yaml:"labels"`         // значения по меткам, если поля не заполнены
+}
+
+type AllocationRuleConfig struct {
+	Slots    int     `yaml:"slots"`
+	Fraction float64 `yaml:"fraction"` // от 0 до 1
+}
+
+// AffinityConfig задает учет переключения слотов между проектами
+type AffinityConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Key        string `yaml:"key"`         // project (по умолчанию), parent — строка-родитель в структуре, или поле Jira
+	SwitchCost string `yaml:"switch_cost"` // время на переключение слота на другой проект в формате Gantt: 4h
+	MaxWait    string `yaml:"max_wait"`    // насколько позже может начаться задача, чтобы остаться в слоте своего проекта
+}
+
+// WIPLimitConfig ограничивает количество одновременно выполняемых задач одного эпика, проекта или компонента
+type WIPLimitConfig struct {
+	Key    string         `yaml:"key"`    // project, parent, component или поле Jira
+	Limit  int            `yaml:"limit"`  // для всех значений ключа, 0 — только для значений из values
+	Values map[string]int `yaml:"values"` // ограничения для отдельных значений
+}
+
+// StabilityConfig задает стабильность ближайшего плана между запусками
+type StabilityConfig struct {
+	FreezeDays int    `yaml:"freeze_days"` // задачи, которые начинаются в ближайшие N рабочих дней, закрепляются
+	MaxShift   string `yaml:"max_shift"`   // наибольший сдвиг начала задачи за запуск в формате Gantt: 5d
+}
+
+// BuffersConfig задает запас времени после задач и в конце проекта
+type BuffersConfig struct {
+	AfterTask        string  `yaml:"after_task"`         // после каждой задачи в формате Gantt: 4h
+	AfterTaskPercent float64 `yaml:"after_task_percent"` // после каждой задачи в процентах от ее длительности
+	Project          string  `yaml:"project"`            // в конце проекта в формате Gantt: 5d
+	ProjectPercent   float64 `yaml:"project_percent"`    // в конце проекта в процентах от длины плана
+	RoundStart       bool    `yaml:"round_start"`        // задачи начинаются только с начала рабочего дня
+}
+
+// MilestonesConfig задает определение вех и их постановку при выравнивании
+type MilestonesConfig struct {
+	IssueTypes     []string `yaml:"issue_types"`     // типы задач-вех
+	GanttAttribute string   `yaml:"gantt_attribute"` // атрибут структуры, непустое значение которого отмечает веху
+	Action         string   `yaml:"action"`          // anchor (по умолчанию) или skip
+}
+
+// HorizonConfig задает горизонт планирования, после которого задачи не выравниваются
+type HorizonConfig struct {
+	DateId   int    `yaml:"date_id"`
+	Duration string `yaml:"duration"` // рабочее время от даты начала выравнивания в формате Gantt: 120d
+	Action   string `yaml:"action"`   // keep (по умолчанию), reset или backlog
+}
+
+// ProgressConfig задает учет состояния задач при выравнивании
+type ProgressConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Done           string `yaml:"done"`            // exclude (по умолчанию) или pin
+	GanttAttribute string `yaml:"gantt_attribute"` // атрибут структуры с прогрессом задачи, например progress
+}
+
+// DeadlinesConfig задает получение сроков задач и отчет об опозданиях
+type DeadlinesConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	Field                 string `yaml:"field"`                   // поле со сроком, по умолчанию duedate
+	FixVersions           bool   `yaml:"fix_versions"`            // без срока в поле — дата выпуска ближайшей версии исправления
+	EarliestDeadlineFirst bool   `yaml:"earliest_deadline_first"` // сначала задачи с ближайшим сроком
+}
+
+// AnnealConfig задает оптимизацию порядка задач имитацией отжига
+type AnnealConfig struct {
+	Objective       string             `yaml:"objective"`   // weighted_tardiness (по умолчанию) или makespan
+	Iterations      int                `yaml:"iterations"`  // по умолчанию 20000
+	TimeBudget      string             `yaml:"time_budget"` // ограничение времени, например 2m, по умолчанию 1m, 0 — без ограничения
+	Seed            int64              `yaml:"seed"`        // по умолчанию 1, один и тот же seed дает один и тот же результат
+	WeightField     string             `yaml:"weight_field"`
+	PriorityWeights map[string]float64 `yaml:"priority_weights"`
+}
+
+// CalendarConfig задает дни, которые накладываются поверх календаря Ганта при выравнивании
+type CalendarConfig struct {
+	Days     []CalendarDayConfig `yaml:"days"`
+	ICSFile  string              `yaml:"ics_file"`
+	ICSHours float64             `yaml:"ics_hours"` // рабочих часов в дни из ics-файла, по умолчанию 0 — выходной
+}
+
+type CalendarDayConfig struct {
+	DateId   int     `yaml:"date_id"`
+	ToDateId int     `yaml:"to_date_id"` // включительно, если нужно задать интервал
+	Hours    float64 `yaml:"hours"`
+	Comment  string  `yaml:"comment"`
+}
+
+// ForestSourceConfig задает отбор задач из структуры, если задачи берутся в порядке структуры
+type ForestSourceConfig struct {
+	MinDepth   int      `yaml:"min_depth"`
+	MaxDepth   *int     `yaml:"max_depth"`
+	IssueTypes []string `yaml:"issue_types"`
+	JQL        string   `yaml:"jql"` // дополнительный фильтр, сортировка игнорируется
+}
+
+// SortKeyConfig задает ключ сортировки задач перед выравниванием
+type SortKeyConfig struct {
+	Key     string   `yaml:"key"`     // поле Jira (priority, duedate, customfield_10100), gantt.manualStart, forest или source
+	Order   string   `yaml:"order"`   // asc (по умолчанию) или desc
+	Missing string   `yaml:"missing"` // last (по умолчанию) или first — куда ставить задачи без значения
+	Values  []string `yaml:"values"`  // явный порядок значений, например приоритетов по имени
+}
+
+// HierarchyConfig задает выравнивание с учетом иерархии структуры
+type HierarchyConfig struct {
+	SkipParents          bool `yaml:"skip_parents"`           // строки-родители не выравниваются, их задержка сбрасывается
+	KeepChildrenTogether bool `yaml:"keep_children_together"` // дочерние задачи одного родителя выравниваются подряд в одном слоте
+}
+
+// ResourceConfig описывает доступность исполнителя, закрепленного за слотом
+type ResourceConfig struct {
+	Name        string              `yaml:"name"`
+	WeekHours   []float64           `yaml:"week_hours"` // 7 значений начиная с понедельника
+	StartDateId int                 `yaml:"start_date_id"`
+	EndDateId   int                 `yaml:"end_date_id"`
+	Absences    []CalendarDayConfig `yaml:"absences"`
+}
+
+// PoolConfig описывает пул слотов для задач одного вида работ (backend, frontend, QA)
+type PoolConfig struct {
+	Name             string                 `yaml:"name"`
+	Capacity         int                    `yaml:"capacity"`          // количество слотов без закрепленного ресурса
+	CapacitySchedule []CapacityPeriodConfig `yaml:"capacity_schedule"` // количество слотов по датам
+	Resources        []string               `yaml:"resources"`         // ресурсы пула по имени, каждый получает свой слот
+	Match            PoolMatchConfig        `yaml:"match"`
+	Shared           string                 `yaml:"shared"` // имя общего пула из shared_pools вместо capacity и resources
+}
+
+// CapacityPeriodConfig задает количество слотов в интервале дат, в остальные дни действует capacity
+type CapacityPeriodConfig struct {
+	FromDateId int    `yaml:"from_date_id"` // 0 — с начала
+	ToDateId   int    `yaml:"to_date_id"`   // включительно, 0 — без ограничения
+	Slots      int    `yaml:"slots"`
+	Comment    string `yaml:"comment"`
+}
+
+// PoolMatchConfig задает правило отбора задач в пул. Все указанные условия должны выполняться,
+// внутри списка достаточно одного совпадения. Пустое правило подходит для любой задачи.
+type PoolMatchConfig struct {
+	Components []string `yaml:"components"`
+	Labels     []string `yaml:"labels"`
+	IssueTypes []string `yaml:"issue_types"`
+	Field      string   `yaml:"field"`  // поле Jira по ID или имени
+	Values     []string `yaml:"values"` // значения поля field
+}
+
+// SharedPoolConfig описывает слоты команды, которая работает над задачами нескольких структур
+type SharedPoolConfig struct {
+	Capacity         int                    `yaml:"capacity"`
+	CapacitySchedule []CapacityPeriodConfig `yaml:"capacity_schedule"`
+	Resources        []ResourceConfig       `yaml:"resources"`
+	ResourcesFile    string                 `yaml:"resources_file"`
+}
+
+// IssueRuleConfig закрепляет, исключает или направляет в пул и слот задачи, подходящие под условия
+type IssueRuleConfig struct {
+	Match  IssueMatchConfig `yaml:"match"`
+	Action string           `yaml:"action"` // pin, exclude или pool
+	Pool   string           `yaml:"pool"`
+	Slot   *int             `yaml:"slot"` // номер слота в пуле начиная с 0
+}
+
+// IssueMatchConfig — условия правила в дополнение к условиям пула. Все указанные условия должны выполняться.
+type IssueMatchConfig struct {
+	PoolMatchConfig `yaml:",inline"`
+	Statuses        []string `yaml:"statuses"`
+	JQL             string   `yaml:"jql"` // дополнительный фильтр, выполняется для задач структуры
 }
 
 type FileConfig struct {
-	Client     ClientConfig               `yaml:"client"`
-	Structures map[string]StructureConfig `yaml:"structures"`
+	Client      ClientConfig                `yaml:"client"`
+	Structures  map[string]StructureConfig  `yaml:"structures"`
+	SharedPools map[string]SharedPoolConfig `yaml:"shared_pools"`
 }
 
 func loadConfig(path string) (*FileConfig, error) {
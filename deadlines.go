@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// resolveDeadlines выставляет задачам срок из поля или из даты выпуска ближайшей версии исправления
+func resolveDeadlines(tasks []*LevelingTask, cfg DeadlinesConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	field := cfg.Field
+	if field == "" {
+		field = "duedate"
+	}
+
+	var withDeadline int
+	for _, task := range tasks {
+		if t, ok := task.Issue.FieldTime(field); ok {
+			task.Deadline = t
+		} else if cfg.FixVersions {
+			task.Deadline = earliestReleaseDate(task.Issue.FixVersions())
+		}
+		if !task.Deadline.IsZero() {
+			withDeadline++
+		}
+	}
+	log.Printf("Задач со сроком: %d из %d\n", withDeadline, len(tasks))
+}
+
+func earliestReleaseDate(versions []JiraVersion) time.Time {
+	var earliest time.Time
+	for _, v := range versions {
+		t, err := time.Parse("2006-01-02", v.ReleaseDate)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	return earliest
+}
+
+// Lateness описывает задачу, которая по расчету завершится после срока
+type Lateness struct {
+	Task           *LevelingTask
+	FinishDateId   int
+	DeadlineDateId int
+	Days           int // опоздание в календарных днях
+}
+
+// findLateTasks рассчитывает дату окончания задач со сроком и возвращает опаздывающие задачи,
+// начиная с наибольшего опоздания
+func findLateTasks(tasks []*LevelingTask, timeline Timeline) []Lateness {
+	var late []Lateness
+	for _, task := range tasks {
+		if task.Deadline.IsZero() || task.Summary || task.PlacementErr != nil {
+			continue
+		}
+		finishDateId, err := timeline.FinishDateForOffset(task.Finish)
+		if err != nil {
+			log.Printf("[WARNING] Не удалось определить дату окончания задачи %s: %v\n", task.Issue.Key, err)
+			continue
+		}
+		deadlineDateId := dateIdFromTime(task.Deadline)
+		if finishDateId <= deadlineDateId {
+			continue
+		}
+		finish, _ := parseDateId(finishDateId)
+		deadline, _ := parseDateId(deadlineDateId)
+		late = append(late, Lateness{
+			Task:           task,
+			FinishDateId:   finishDateId,
+			DeadlineDateId: deadlineDateId,
+			Days:           int(finish.Sub(deadline).Hours() / 24),
+		})
+	}
+	sort.SliceStable(late, func(i, j int) bool { return late[i].Days > late[j].Days })
+	return late
+}
+
+func reportLateness(tasks []*LevelingTask, timeline Timeline) {
+	var withDeadline int
+	for _, task := range tasks {
+		if !task.Deadline.IsZero() && !task.Summary && task.PlacementErr == nil {
+			withDeadline++
+		}
+	}
+	if withDeadline == 0 {
+		return
+	}
+
+	late := findLateTasks(tasks, timeline)
+	log.Printf("Задачи с опозданием: %d из %d со сроком\n", len(late), withDeadline)
+	for _, l := range late {
+		log.Printf("  %s: окончание %d, срок %d, опоздание %d дн.\n", l.Task.Issue.Key, l.FinishDateId, l.DeadlineDateId, l.Days)
+	}
+}
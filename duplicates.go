@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Политики выравнивания задач, которые встречаются в структуре несколько раз
+const (
+	DuplicatesFirst = "first" // выравнивается первая строка, остальные не меняются
+	DuplicatesAll   = "all"   // всем строкам выставляется одинаковая задержка
+	DuplicatesFail  = "fail"  // выравнивание не выполняется
+)
+
+// selectIssueRows возвращает строки, которым нужно выставить задержку, для каждой задачи из списка.
+// Первая строка в списке — та, по атрибутам которой рассчитывается задержка.
+func selectIssueRows(forest *Forest, issues []JiraIssue, policy string) (map[string][]int, error) {
+	if policy == "" {
+		policy = DuplicatesFirst
+	}
+	if policy != DuplicatesFirst && policy != DuplicatesAll && policy != DuplicatesFail {
+		return nil, fmt.Errorf("неизвестная политика для повторяющихся задач: '%s'", policy)
+	}
+
+	all := forest.IssueRows()
+	result := make(map[string][]int, len(issues))
+	var duplicates []string
+	for _, issue := range issues {
+		rows, ok := all[issue.ID]
+		if !ok {
+			continue
+		}
+		if len(rows) > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("%s %v", issue.Key, rows))
+			if policy == DuplicatesFirst {
+				rows = rows[:1]
+			}
+		}
+		result[issue.ID] = rows
+	}
+
+	if len(duplicates) > 0 {
+		log.Printf("[WARNING] Задачи, которые встречаются в структуре несколько раз (%d), политика '%s':\n", len(duplicates), policy)
+		for _, d := range duplicates {
+			log.Printf("  %s\n", d)
+		}
+		if policy == DuplicatesFail {
+			return nil, fmt.Errorf("в структуре есть повторяющиеся задачи: %s", strings.Join(duplicates, ", "))
+		}
+	}
+
+	return result, nil
+}
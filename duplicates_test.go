@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectIssueRows(t *testing.T) {
+	forest := parseForest("1:0:100,2:1:101,3:0:100,4:0:7/5", map[string]string{"7": ItemTypeFolder})
+	issues := []JiraIssue{{ID: "100", Key: "T-1"}, {ID: "101", Key: "T-2"}, {ID: "102", Key: "T-3"}}
+	tests := []struct {
+		policy  string
+		want    map[string][]int
+		wantErr bool
+	}{
+		{policy: "", want: map[string][]int{"100": {1}, "101": {2}}},
+		{policy: DuplicatesFirst, want: map[string][]int{"100": {1}, "101": {2}}},
+		{policy: DuplicatesAll, want: map[string][]int{"100": {1, 3}, "101": {2}}},
+		{policy: DuplicatesFail, wantErr: true},
+		{policy: "last", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := selectIssueRows(forest, issues, tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("политика '%s': ошибка %v", tt.policy, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("политика '%s': строки %v, ожидается %v", tt.policy, got, tt.want)
+		}
+	}
+}
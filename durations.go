@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Источники длительности задачи
+const (
+	DurationSourceGantt             = "gantt"              // длительность из Gantt
+	DurationSourceRemainingEstimate = "remaining_estimate" // оставшаяся оценка
+	DurationSourceOriginalEstimate  = "original_estimate"  // первоначальная оценка
+	DurationSourceStoryPoints       = "story_points"       // story points × hours_per_point
+	DurationSourceIssueType         = "issue_type"         // длительность по умолчанию для типа задачи
+)
+
+// durationSources разбирает настройки длительностей и проверяет цепочку источников
+type durationSources struct {
+	sources      []string
+	hoursPerPt   time.Duration
+	pointsField  string
+	typeDefaults map[string]time.Duration
+	fallback     time.Duration
+}
+
+func newDurationSources(cfg DurationsConfig) (*durationSources, error) {
+	ds := &durationSources{
+		sources:      cfg.Sources,
+		hoursPerPt:   time.Duration(cfg.HoursPerPoint * float64(time.Hour)),
+		pointsField:  cfg.StoryPointsField,
+		typeDefaults: make(map[string]time.Duration, len(cfg.IssueTypeDefaults)),
+	}
+	if len(ds.sources) == 0 {
+		ds.sources = []string{DurationSourceGantt}
+	}
+
+	for _, source := range ds.sources {
+		switch source {
+		case DurationSourceGantt, DurationSourceRemainingEstimate, DurationSourceOriginalEstimate:
+		case DurationSourceStoryPoints:
+			if ds.pointsField == "" || ds.hoursPerPt <= 0 {
+				return nil, fmt.Errorf("для источника длительности %s нужно указать story_points_field и hours_per_point", source)
+			}
+		case DurationSourceIssueType:
+		default:
+			return nil, fmt.Errorf("неизвестный источник длительности: '%s'", source)
+		}
+	}
+
+	for issueType, value := range cfg.IssueTypeDefaults {
+		d, err := parseGanttDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("длительность по умолчанию для типа %s: %w", issueType, err)
+		}
+		ds.typeDefaults[strings.ToLower(issueType)] = d
+	}
+	if cfg.Default != "" {
+		d, err := parseGanttDuration(cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("длительность по умолчанию: %w", err)
+		}
+		ds.fallback = d
+	}
+
+	return ds, nil
+}
+
+// resolve возвращает длительность задачи из первого источника, в котором она задана
+func (ds *durationSources) resolve(task *LevelingTask) (time.Duration, string) {
+	for _, source := range ds.sources {
+		var d time.Duration
+		switch source {
+		case DurationSourceGantt:
+			d = task.Attributes.Duration
+		case DurationSourceRemainingEstimate:
+			d, _ = task.Issue.RemainingEstimate()
+		case DurationSourceOriginalEstimate:
+			d, _ = task.Issue.OriginalEstimate()
+		case DurationSourceStoryPoints:
+			if points, ok := task.Issue.FieldNumber(ds.pointsField); ok {
+				d = time.Duration(points * float64(ds.hoursPerPt))
+			}
+		case DurationSourceIssueType:
+			var ok bool
+			if d, ok = ds.typeDefaults[strings.ToLower(task.Issue.IssueType())]; !ok {
+				d = ds.fallback
+			}
+		}
+		if d > 0 {
+			return d, source
+		}
+	}
+	return 0, ""
+}
+
+// resolveDurations выставляет задачам длительность по цепочке источников и выводит отчет
+func resolveDurations(tasks []*LevelingTask, cfg DurationsConfig) error {
+	ds, err := newDurationSources(cfg)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		task.Duration, task.DurationSource = ds.resolve(task)
+		counts[task.DurationSource]++
+		if task.DurationSource != DurationSourceGantt {
+			source := task.DurationSource
+			if source == "" {
+				source = "не определена"
+			}
+			log.Printf("Длительность задачи %s: %s (%s)\n", task.Issue.Key, task.Duration, source)
+		}
+	}
+
+	sources := make([]string, 0, len(counts))
+	for source := range counts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		name := source
+		if name == "" {
+			name = "не определена"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", name, counts[source]))
+	}
+	log.Printf("Источники длительности задач: %s\n", strings.Join(parts, ", "))
+	return nil
+}
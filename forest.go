@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ItemTypeIssue     = "com.almworks.jira.structure:type-issue"
+	ItemTypeFolder    = "com.almworks.jira.structure:type-folder"
+	ItemTypeGenerator = "com.almworks.jira.structure:type-generator"
+)
+
+// ForestRow описывает строку структуры
+type ForestRow struct {
+	ID       int
+	Depth    int
+	Parent   int    // ID родительской строки, 0 — строка верхнего уровня
+	ItemType string // полный ключ типа элемента, например com.almworks.jira.structure:type-issue
+	ItemID   string // идентификатор элемента внутри типа, для задач — ID задачи
+	Index    int    // позиция строки в лесу сверху вниз
+}
+
+func (r ForestRow) IsIssue() bool {
+	return r.ItemType == ItemTypeIssue
+}
+
+// ShortItemType возвращает тип элемента без префикса плагина: issue, folder, generator и т.д.
+func (r ForestRow) ShortItemType() string {
+	t := r.ItemType
+	if i := strings.LastIndex(t, ":"); i >= 0 {
+		t = t[i+1:]
+	}
+	return strings.TrimPrefix(t, "type-")
+}
+
+// Forest — иерархия строк структуры в порядке отображения
+type Forest struct {
+	Rows []ForestRow
+
+	byID     map[int]int
+	children map[int][]int
+}
+
+// parseForest разбирает формулу леса вида "rowId:depth:itemIdentity,...".
+// itemIdentity для задачи — ID задачи, для остальных элементов — "typeId/itemId", где typeId ссылается на itemTypes.
+// Некорректные элементы пропускаются с предупреждением.
+func parseForest(formula string, itemTypes map[string]string) *Forest {
+	f := &Forest{
+		byID:     make(map[int]int),
+		children: make(map[int][]int),
+	}
+	if strings.TrimSpace(formula) == "" {
+		return f
+	}
+
+	// Стек родителей по глубине
+	var parents []int
+	for _, item := range strings.Split(formula, ",") {
+		parts := strings.Split(item, ":")
+		if len(parts) < 3 {
+			log.Printf("[WARNING] Некорректный элемент формулы леса '%s' будет пропущен\n", item)
+			continue
+		}
+		rowID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			log.Printf("[WARNING] Некорректный rowID в элементе формулы леса '%s', элемент будет пропущен\n", item)
+			continue
+		}
+		depth, err := strconv.Atoi(parts[1])
+		if err != nil {
+			log.Printf("[WARNING] Некорректная глубина в элементе формулы леса '%s', элемент будет пропущен\n", item)
+			continue
+		}
+		if depth < 0 || depth > len(parents) {
+			log.Printf("[WARNING] Некорректная глубина %d строки %d, строка будет пропущена\n", depth, rowID)
+			continue
+		}
+
+		row := ForestRow{ID: rowID, Depth: depth, Index: len(f.Rows)}
+		identity := parts[2]
+		if typeID, itemID, ok := strings.Cut(identity, "/"); ok {
+			row.ItemType = itemTypes[typeID]
+			if row.ItemType == "" {
+				row.ItemType = typeID
+			}
+			row.ItemID = itemID
+		} else {
+			row.ItemType = ItemTypeIssue
+			row.ItemID = identity
+		}
+
+		parents = parents[:depth]
+		if depth > 0 {
+			row.Parent = parents[depth-1]
+		}
+		parents = append(parents, rowID)
+
+		f.byID[rowID] = len(f.Rows)
+		f.children[row.Parent] = append(f.children[row.Parent], rowID)
+		f.Rows = append(f.Rows, row)
+	}
+
+	return f
+}
+
+func (f *Forest) Row(rowID int) (ForestRow, bool) {
+	i, ok := f.byID[rowID]
+	if !ok {
+		return ForestRow{}, false
+	}
+	return f.Rows[i], true
+}
+
+// Children возвращает ID дочерних строк, для 0 — строки верхнего уровня
+func (f *Forest) Children(rowID int) []int {
+	return f.children[rowID]
+}
+
+func (f *Forest) HasChildren(rowID int) bool {
+	return len(f.children[rowID]) > 0
+}
+
+// IssueRows возвращает все строки каждой задачи в порядке леса
+func (f *Forest) IssueRows() map[string][]int {
+	m := make(map[string][]int)
+	for _, row := range f.Rows {
+		if row.IsIssue() {
+			m[row.ItemID] = append(m[row.ItemID], row.ID)
+		}
+	}
+	return m
+}
+
+// TypeSummary возвращает строку с количеством строк каждого типа, например "issue: 10, folder: 2"
+func (f *Forest) TypeSummary() string {
+	counts := make(map[string]int)
+	for _, row := range f.Rows {
+		counts[row.ShortItemType()]++
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%s: %d", t, counts[t]))
+	}
+	return strings.Join(parts, ", ")
+}
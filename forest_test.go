@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseForest(t *testing.T) {
+	itemTypes := map[string]string{"7": ItemTypeFolder, "9": ItemTypeGenerator}
+	tests := []struct {
+		name    string
+		formula string
+		want    []ForestRow
+	}{
+		{
+			name:    "пустая формула",
+			formula: " ",
+		},
+		{
+			name:    "вложенность и возврат на верхний уровень",
+			formula: "1:0:100,2:1:101,3:2:102,4:1:103,5:0:104",
+			want: []ForestRow{
+				{ID: 1, Depth: 0, ItemType: ItemTypeIssue, ItemID: "100", Index: 0},
+				{ID: 2, Depth: 1, Parent: 1, ItemType: ItemTypeIssue, ItemID: "101", Index: 1},
+				{ID: 3, Depth: 2, Parent: 2, ItemType: ItemTypeIssue, ItemID: "102", Index: 2},
+				{ID: 4, Depth: 1, Parent: 1, ItemType: ItemTypeIssue, ItemID: "103", Index: 3},
+				{ID: 5, Depth: 0, ItemType: ItemTypeIssue, ItemID: "104", Index: 4},
+			},
+		},
+		{
+			name:    "папки и генераторы через itemTypes",
+			formula: "1:0:7/12,2:1:9/3,3:2:100,4:0:5/8",
+			want: []ForestRow{
+				{ID: 1, Depth: 0, ItemType: ItemTypeFolder, ItemID: "12", Index: 0},
+				{ID: 2, Depth: 1, Parent: 1, ItemType: ItemTypeGenerator, ItemID: "3", Index: 1},
+				{ID: 3, Depth: 2, Parent: 2, ItemType: ItemTypeIssue, ItemID: "100", Index: 2},
+				{ID: 4, Depth: 0, ItemType: "5", ItemID: "8", Index: 3}, // неизвестный тип остается как есть
+			},
+		},
+		{
+			name:    "некорректные элементы пропускаются",
+			formula: "1:0:100,x:0:101,2:y:102,3:0,4:-1:103,5:1:104",
+			want: []ForestRow{
+				{ID: 1, Depth: 0, ItemType: ItemTypeIssue, ItemID: "100", Index: 0},
+				{ID: 5, Depth: 1, Parent: 1, ItemType: ItemTypeIssue, ItemID: "104", Index: 1},
+			},
+		},
+		{
+			name:    "пропуск уровня глубины",
+			formula: "1:0:100,2:2:101,3:1:102",
+			want: []ForestRow{
+				{ID: 1, Depth: 0, ItemType: ItemTypeIssue, ItemID: "100", Index: 0},
+				{ID: 3, Depth: 1, Parent: 1, ItemType: ItemTypeIssue, ItemID: "102", Index: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := parseForest(tt.formula, itemTypes)
+			if !reflect.DeepEqual(f.Rows, tt.want) {
+				t.Errorf("строки леса:\n%+v\nожидается:\n%+v", f.Rows, tt.want)
+			}
+			for _, row := range tt.want {
+				if got, ok := f.Row(row.ID); !ok || got != row {
+					t.Errorf("Row(%d) = %+v, %v", row.ID, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestParseForestChildren(t *testing.T) {
+	f := parseForest("1:0:100,2:1:101,3:1:102,4:0:103", nil)
+
+	if got := f.Children(0); !reflect.DeepEqual(got, []int{1, 4}) {
+		t.Errorf("строки верхнего уровня %v, ожидается [1 4]", got)
+	}
+	if got := f.Children(1); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("дочерние строки 1: %v, ожидается [2 3]", got)
+	}
+	if f.HasChildren(4) {
+		t.Error("у строки 4 нет дочерних строк")
+	}
+}
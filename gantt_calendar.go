@@ -22,27 +22,25 @@ type Calendar struct {
 }
 
 func (c *Calendar) GetWorkingDurationForDate(dateId int) time.Duration {
+	return c.GetScheduleForDate(dateId).Duration
+}
+
+func (c *Calendar) GetScheduleForDate(dateId int) DaySchedule {
 	if day, ok := c.CustomDays[dateId]; ok {
-		return day.Duration
+		return day
 	}
 
 	t, err := parseDateId(dateId)
 	if err != nil {
-		return 0
-	}
-
-	weekday := int(t.Weekday())
-	if weekday == 0 {
-		weekday = 6
-	} else {
-		weekday = weekday - 1
+		return DaySchedule{}
 	}
 
+	weekday := mondayBasedWeekday(t)
 	if weekday >= 0 && weekday < len(c.WeekDays) {
-		return c.WeekDays[weekday].Duration
+		return c.WeekDays[weekday]
 	}
 
-	return 0
+	return DaySchedule{}
 }
 
 func (c *Calendar) GetWorkingDurationBetween(startDateId, finishDateId int) time.Duration {
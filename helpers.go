@@ -20,6 +20,14 @@ func parseTimeId(id int) time.Duration {
 	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
 }
 
+func timeIdFromDuration(d time.Duration) int {
+	d = d.Round(time.Second)
+	hours := int(d / time.Hour)
+	minutes := int(d%time.Hour) / int(time.Minute)
+	seconds := int(d%time.Minute) / int(time.Second)
+	return hours*10000 + minutes*100 + seconds
+}
+
 func parseDateId(dateId int) (time.Time, error) {
 	dateStr := strconv.Itoa(dateId)
 	return time.Parse("20060102", dateStr)
@@ -52,6 +60,41 @@ func parseGanttDuration(input string) (time.Duration, error) {
 	return total, nil
 }
 
+// mondayBasedWeekday возвращает номер дня недели, где 0 — понедельник, 6 — воскресенье
+func mondayBasedWeekday(t time.Time) int {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		return 6
+	}
+	return weekday - 1
+}
+
 func dateIdFromTime(t time.Time) int {
 	return t.Year()*10000 + int(t.Month())*100 + t.Day()
 }
+
+func nextDateId(dateId int) (int, error) {
+	t, err := parseDateId(dateId)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная дата %d: %w", dateId, err)
+	}
+	return dateIdFromTime(t.AddDate(0, 0, 1)), nil
+}
+
+// dateIdsBetween возвращает даты из интервала [startDateId, finishDateId)
+func dateIdsBetween(startDateId, finishDateId int) []int {
+	startDate, err := parseDateId(startDateId)
+	if err != nil {
+		return nil
+	}
+	endDate, err := parseDateId(finishDateId)
+	if err != nil {
+		return nil
+	}
+
+	var ids []int
+	for d := startDate; d.Before(endDate); d = d.AddDate(0, 0, 1) {
+		ids = append(ids, dateIdFromTime(d))
+	}
+	return ids
+}
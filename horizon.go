@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Действия с задачами за горизонтом планирования
+const (
+	HorizonKeep    = "keep"    // задержка не записывается, задача остается в текущих датах
+	HorizonReset   = "reset"   // задержка сбрасывается
+	HorizonBacklog = "backlog" // задача ставится на границу горизонта
+)
+
+// parseHorizonConfig проверяет горизонт планирования и возвращает его длительность от даты начала выравнивания
+func parseHorizonConfig(cfg HorizonConfig) (time.Duration, error) {
+	switch cfg.Action {
+	case "", HorizonKeep, HorizonReset, HorizonBacklog:
+	default:
+		return 0, fmt.Errorf("неизвестное действие с задачами за горизонтом планирования: '%s'", cfg.Action)
+	}
+	if cfg.DateId != 0 && cfg.Duration != "" {
+		return 0, fmt.Errorf("горизонт планирования задается либо date_id, либо duration")
+	}
+	if cfg.DateId != 0 {
+		if _, err := parseDateId(cfg.DateId); err != nil || cfg.DateId < 0 {
+			return 0, fmt.Errorf("некорректная дата горизонта планирования: %d", cfg.DateId)
+		}
+	}
+	if cfg.Duration == "" {
+		return 0, nil
+	}
+	d, err := parseGanttDuration(cfg.Duration)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная длительность горизонта планирования: %w", err)
+	}
+	return d, nil
+}
+
+// validateHorizonStart проверяет, что горизонт планирования не раньше даты начала выравнивания:
+// иначе за горизонтом оказались бы все задачи
+func validateHorizonStart(cfg HorizonConfig, startDateId int) error {
+	if cfg.DateId > 0 && cfg.DateId < startDateId {
+		return fmt.Errorf("горизонт планирования %d раньше даты начала выравнивания %d", cfg.DateId, startDateId)
+	}
+	return nil
+}
+
+// horizonOffset возвращает смещение горизонта планирования от начала диаграммы, false — горизонт не задан
+func horizonOffset(cfg HorizonConfig, duration time.Duration, timeline Timeline, startDateId int) (time.Duration, bool) {
+	switch {
+	case cfg.DateId > 0:
+		return timeline.OffsetForDate(cfg.DateId), true
+	case duration > 0:
+		return max(0, timeline.OffsetForDate(startDateId)) + duration, true
+	}
+	return 0, false
+}
+
+func horizonAction(cfg HorizonConfig) string {
+	if cfg.Action == "" {
+		return HorizonKeep
+	}
+	return cfg.Action
+}
+
+// applyHorizon находит задачи, которые по расчету начинаются после горизонта планирования,
+// применяет к ним действие из настроек и возвращает количество таких задач
+func applyHorizon(tasks []*LevelingTask, cfg HorizonConfig, horizon time.Duration, timeline Timeline) int {
+	action := horizonAction(cfg)
+	var beyond int
+	for _, task := range tasks {
+		if task.Summary || task.Pinned || task.HasManualDates() || task.PlacementErr != nil || task.LevelingDelay <= horizon {
+			continue
+		}
+		beyond++
+		task.Horizon = action
+		// Окончание сдвигается вместе с началом, чтобы отчеты показывали записанные в Gantt даты
+		switch action {
+		case HorizonKeep:
+			if current, ok := currentStart(task, timeline); ok {
+				task.Finish += current - task.LevelingDelay
+				task.LevelingDelay = current
+			}
+		case HorizonReset:
+			task.Finish -= task.LevelingDelay
+			task.LevelingDelay = 0
+		case HorizonBacklog:
+			task.Finish += horizon - task.LevelingDelay
+			task.LevelingDelay = horizon
+		}
+	}
+	return beyond
+}
+
+func reportHorizon(cfg HorizonConfig, horizon time.Duration, timeline Timeline, beyond int) {
+	horizonDateId, err := timeline.FinishDateForOffset(horizon)
+	if err != nil {
+		horizonDateId = cfg.DateId
+	}
+	log.Printf("Горизонт планирования %d: за горизонтом задач %d (%s)\n", horizonDateId, beyond, horizonAction(cfg))
+}
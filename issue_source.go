@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Источники списка задач для выравнивания
+const (
+	IssueSourceJQL    = "jql"    // задачи и их порядок берутся из JQL-запроса
+	IssueSourceForest = "forest" // задачи и их порядок берутся из структуры
+)
+
+// forestIssueChunk ограничивает количество ID задач в одном JQL-запросе
+const forestIssueChunk = 200
+
+var orderByRe = regexp.MustCompile(`(?is)\s*order\s+by\s.*$`)
+
+// getLevelingIssues возвращает задачи для выравнивания в порядке приоритета
+func getLevelingIssues(client *JiraClient, structure StructureConfig, forest *Forest) ([]JiraIssue, error) {
+	fields := issueFields(structure)
+	switch structure.Source {
+	case "", IssueSourceJQL:
+		if structure.JQL == "" {
+			return nil, fmt.Errorf("не указан jql для структуры %d", structure.ID)
+		}
+		log.Printf("Получаем список задач по JQL: '%s'\n", structure.JQL)
+		issues, err := client.GetIssues(structure.JQL, fields)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка зададач: %w", err)
+		}
+		return issues, nil
+	case IssueSourceForest:
+		log.Printf("Получаем список задач в порядке структуры %d\n", structure.ID)
+		issues, err := getForestIssues(client, forest, structure.Forest, fields)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка получения списка зададач: %w", err)
+		}
+		return issues, nil
+	default:
+		return nil, fmt.Errorf("неизвестный источник задач: '%s'", structure.Source)
+	}
+}
+
+// getForestIssues возвращает задачи структуры в порядке строк сверху вниз.
+// Если задача встречается несколько раз, ее позиция определяется первой строкой.
+func getForestIssues(client *JiraClient, forest *Forest, cfg ForestSourceConfig, fields []string) ([]JiraIssue, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, row := range forest.Rows {
+		if !row.IsIssue() || seen[row.ItemID] {
+			continue
+		}
+		if row.Depth < cfg.MinDepth || (cfg.MaxDepth != nil && row.Depth > *cfg.MaxDepth) {
+			continue
+		}
+		seen[row.ItemID] = true
+		ids = append(ids, row.ItemID)
+	}
+
+	// Jira используется только как фильтр: задачи, не прошедшие фильтр, исключаются
+	var filters []string
+	if cfg.JQL != "" {
+		filters = append(filters, "("+orderByRe.ReplaceAllString(cfg.JQL, "")+")")
+	}
+	if len(cfg.IssueTypes) > 0 {
+		quoted := make([]string, 0, len(cfg.IssueTypes))
+		for _, t := range cfg.IssueTypes {
+			quoted = append(quoted, fmt.Sprintf("%q", t))
+		}
+		filters = append(filters, fmt.Sprintf("issuetype in (%s)", strings.Join(quoted, ", ")))
+	}
+
+	found := make(map[string]JiraIssue, len(ids))
+	for start := 0; start < len(ids); start += forestIssueChunk {
+		end := min(start+forestIssueChunk, len(ids))
+		jql := strings.Join(append([]string{fmt.Sprintf("id in (%s)", strings.Join(ids[start:end], ", "))}, filters...), " AND ")
+		issues, err := client.GetIssues(jql, fields)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			found[issue.ID] = issue
+		}
+	}
+
+	issues := make([]JiraIssue, 0, len(found))
+	for _, id := range ids {
+		if issue, ok := found[id]; ok {
+			issues = append(issues, issue)
+		}
+	}
+	log.Printf("Задач в структуре: %d, после фильтрации: %d\n", len(ids), len(issues))
+	return issues, nil
+}
+
+// filterIssueIDs возвращает ID задач из списка, которые проходят JQL-фильтр
+func filterIssueIDs(client *JiraClient, ids []string, jql string) (map[string]bool, error) {
+	filter := "(" + orderByRe.ReplaceAllString(jql, "") + ")"
+	matched := make(map[string]bool)
+	for start := 0; start < len(ids); start += forestIssueChunk {
+		end := min(start+forestIssueChunk, len(ids))
+		issues, err := client.GetIssues(fmt.Sprintf("id in (%s) AND %s", strings.Join(ids[start:end], ", "), filter), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			matched[issue.ID] = true
+		}
+	}
+	return matched, nil
+}
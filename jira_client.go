@@ -16,6 +16,8 @@ type JiraClient struct {
 	BaseURL string
 
 	HTTPClient *http.Client
+
+	fieldIDs map[string]string // кэш соответствия имен полей их ID
 }
 
 type jiraClientTransportWrapper struct {
@@ -43,8 +45,9 @@ func (t *jiraClientTransportWrapper) RoundTrip(req *http.Request) (*http.Respons
 }
 
 type JiraIssue struct {
-	ID  string `json:"id"`
-	Key string `json:"key"`
+	ID     string                     `json:"id"`
+	Key    string                     `json:"key"`
+	Fields map[string]json.RawMessage `json:"fields"`
 }
 
 type StructureRowAttributes struct {
@@ -55,6 +58,8 @@ type StructureRowAttributes struct {
 	Finish       time.Time
 	Signature    int64
 	Version      int
+
+	Extra map[string]string // текстовые значения дополнительно запрошенных атрибутов
 }
 
 type GanttMeta struct {
@@ -95,8 +100,10 @@ func NewJiraClient(cfg ClientConfig) *JiraClient {
 
 // --- Методы ---
 
-func (c *JiraClient) GetIssues(jql string) ([]JiraIssue, error) {
-	url := fmt.Sprintf("%s/rest/api/latest/search?jql=%s&maxResults=1000&fields=summary", c.BaseURL, url.QueryEscape(jql))
+// GetIssues возвращает задачи по JQL. Кроме summary запрашиваются дополнительные поля fields.
+func (c *JiraClient) GetIssues(jql string, fields []string) ([]JiraIssue, error) {
+	fieldList := strings.Join(append([]string{"summary"}, fields...), ",")
+	url := fmt.Sprintf("%s/rest/api/latest/search?jql=%s&maxResults=1000&fields=%s", c.BaseURL, url.QueryEscape(jql), url.QueryEscape(fieldList))
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -124,7 +131,7 @@ func (c *JiraClient) GetIssues(jql string) ([]JiraIssue, error) {
 	return result.Issues, nil
 }
 
-func (c *JiraClient) GetForestMapping(structureID int) (map[string]string, error) {
+func (c *JiraClient) GetForest(structureID int) (*Forest, error) {
 	forestSpec := fmt.Sprintf(`{"structureId":%d}`, structureID)
 	forestURL := fmt.Sprintf("%s/rest/structure/2.0/forest/latest?s=%s", c.BaseURL, url.QueryEscape(forestSpec))
 
@@ -155,44 +162,39 @@ func (c *JiraClient) GetForestMapping(structureID int) (map[string]string, error
 		return nil, fmt.Errorf("ошибка парсинга Forest: %w", err)
 	}
 
-	issueIDToRowID := make(map[string]string)
-
-	// Парсим formula
-	formulaItems := strings.Split(forest.Formula, ",")
-	for _, item := range formulaItems {
-		parts := strings.Split(item, ":")
-		if len(parts) < 3 {
-			continue
-		}
-		rowID := parts[0]
-		itemIdentity := parts[2]
-
-		// Только для задач (нет / в itemIdentity)
-		if strings.Contains(itemIdentity, "/") {
-			continue
-		}
+	return parseForest(forest.Formula, forest.ItemTypes), nil
+}
 
-		issueIDToRowID[itemIdentity] = rowID
+func (c *JiraClient) GetRowAttributes(structureID int, rowID int) (*StructureRowAttributes, error) {
+	attributes, err := c.GetRowsAttributes(structureID, []int{rowID}, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	return issueIDToRowID, nil
+	return attributes[rowID], nil
 }
 
-func (c *JiraClient) GetRowAttributes(structureID int, rowID int) (*StructureRowAttributes, error) {
+// GetRowsAttributes получает атрибуты Gantt для нескольких строк одним запросом.
+// Значения атрибутов extra возвращаются в Extra в текстовом виде.
+func (c *JiraClient) GetRowsAttributes(structureID int, rowIDs []int, extra []string) (map[int]*StructureRowAttributes, error) {
+	attributeSpecs := []map[string]string{
+		{"id": "gantt.duration", "format": "text"},
+		{"id": "gantt.manualStart", "format": "text"},
+		{"id": "gantt.manualFinish", "format": "text"},
+		{"id": "gantt.start", "format": "text"},
+		{"id": "gantt.finish", "format": "text"},
+	}
+	for _, id := range extra {
+		attributeSpecs = append(attributeSpecs, map[string]string{"id": id, "format": "text"})
+	}
+
 	url := fmt.Sprintf("%s/rest/structure/2.0/attribute/subscription?valuesUpdate=true&valuesTimeout=500", c.BaseURL)
 
 	requestBody := map[string]interface{}{
 		"forestSpec": map[string]interface{}{
 			"structureId": structureID,
 		},
-		"rows": []int{rowID},
-		"attributes": []map[string]string{
-			{"id": "gantt.duration", "format": "text"},
-			{"id": "gantt.manualStart", "format": "text"},
-			{"id": "gantt.manualFinish", "format": "text"},
-			{"id": "gantt.start", "format": "text"},
-			{"id": "gantt.finish", "format": "text"},
-		},
+		"rows":       rowIDs,
+		"attributes": attributeSpecs,
 	}
 
 	bodyBytes, err := json.Marshal(requestBody)
@@ -233,34 +235,46 @@ func (c *JiraClient) GetRowAttributes(structureID int, rowID int) (*StructureRow
 		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
 	}
 
-	attributes := StructureRowAttributes{
-		Signature: rawResponse.ValuesUpdate.Version.Signature,
-		Version:   rawResponse.ValuesUpdate.Version.Version,
-	}
-
-	// Временные переменные для текстовых значений
-	var durationStr, manulStartStr, manualFinishStr, startStr, finishStr string
-
+	// Текстовые значения атрибутов по строкам
+	values := make(map[string]map[string]string, len(rowIDs))
 	for _, data := range rawResponse.ValuesUpdate.Data {
 		for row, value := range data.Values {
-			if row != fmt.Sprintf("%d", rowID) {
-				continue
+			if values[row] == nil {
+				values[row] = make(map[string]string)
 			}
-			switch data.Attribute.ID {
-			case "gantt.duration":
-				durationStr = value
-			case "gantt.manualStart":
-				manulStartStr = value
-			case "gantt.manualFinish":
-				manualFinishStr = value
-			case "gantt.start":
-				startStr = value
-			case "gantt.finish":
-				finishStr = value
+			values[row][data.Attribute.ID] = value
+		}
+	}
+
+	result := make(map[int]*StructureRowAttributes, len(rowIDs))
+	for _, rowID := range rowIDs {
+		attributes, err := parseRowAttributes(values[fmt.Sprintf("%d", rowID)])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", rowID, err)
+		}
+		attributes.Signature = rawResponse.ValuesUpdate.Version.Signature
+		attributes.Version = rawResponse.ValuesUpdate.Version.Version
+		if len(extra) > 0 {
+			attributes.Extra = make(map[string]string, len(extra))
+			for _, id := range extra {
+				attributes.Extra[id] = values[fmt.Sprintf("%d", rowID)][id]
 			}
 		}
+		result[rowID] = attributes
 	}
 
+	return result, nil
+}
+
+func parseRowAttributes(values map[string]string) (*StructureRowAttributes, error) {
+	var attributes StructureRowAttributes
+
+	durationStr := values["gantt.duration"]
+	manulStartStr := values["gantt.manualStart"]
+	manualFinishStr := values["gantt.manualFinish"]
+	startStr := values["gantt.start"]
+	finishStr := values["gantt.finish"]
+
 	// Парсим даты
 	//layout := "02.Jan.06 15:04 PM" // формат даты из примера ответа //TODO: FIXIT разные Ганты могут отдавать даты в разном формате
 	layout := "02.01.06 15:04"
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultIssueFields запрашиваются для каждой задачи, чтобы решения при выравнивании могли на них опираться
+var defaultIssueFields = []string{
+	"issuetype",
+	"status",
+	"resolution",
+	"priority",
+	"assignee",
+	"duedate",
+	"timeoriginalestimate",
+	"timeestimate",
+	"timespent",
+	"labels",
+	"components",
+	"fixVersions",
+}
+
+type JiraVersion struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ReleaseDate string `json:"releaseDate"`
+	Released    bool   `json:"released"`
+}
+
+// --- Типизированный доступ к полям задачи ---
+
+func (i JiraIssue) Summary() string            { return i.FieldString("summary") }
+func (i JiraIssue) IssueType() string          { return i.FieldString("issuetype") }
+func (i JiraIssue) Status() string             { return i.FieldString("status") }
+func (i JiraIssue) Resolution() string         { return i.FieldString("resolution") }
+func (i JiraIssue) Priority() string           { return i.FieldString("priority") }
+func (i JiraIssue) Labels() []string           { return i.FieldStrings("labels") }
+func (i JiraIssue) Components() []string       { return i.FieldStrings("components") }
+func (i JiraIssue) DueDate() (time.Time, bool) { return i.FieldTime("duedate") }
+
+// Assignee возвращает логин исполнителя, пустую строку — если задача не назначена
+func (i JiraIssue) Assignee() string {
+	var user struct {
+		Name        string `json:"name"`
+		AccountID   string `json:"accountId"`
+		DisplayName string `json:"displayName"`
+	}
+	if !i.decodeField("assignee", &user) {
+		return ""
+	}
+	switch {
+	case user.Name != "":
+		return user.Name
+	case user.AccountID != "":
+		return user.AccountID
+	}
+	return user.DisplayName
+}
+
+// StatusCategory возвращает ключ категории статуса: new, indeterminate или done
+func (i JiraIssue) StatusCategory() string {
+	var status struct {
+		StatusCategory struct {
+			Key string `json:"key"`
+		} `json:"statusCategory"`
+	}
+	if !i.decodeField("status", &status) {
+		return ""
+	}
+	return status.StatusCategory.Key
+}
+
+func (i JiraIssue) OriginalEstimate() (time.Duration, bool) {
+	return i.fieldSeconds("timeoriginalestimate")
+}
+
+func (i JiraIssue) RemainingEstimate() (time.Duration, bool) {
+	return i.fieldSeconds("timeestimate")
+}
+
+func (i JiraIssue) TimeSpent() (time.Duration, bool) {
+	return i.fieldSeconds("timespent")
+}
+
+func (i JiraIssue) FixVersions() []JiraVersion {
+	var versions []JiraVersion
+	i.decodeField("fixVersions", &versions)
+	return versions
+}
+
+// FieldString возвращает строковое представление поля: строки и числа как есть,
+// у объектов — value, name или displayName, у массивов — значения через запятую
+func (i JiraIssue) FieldString(field string) string {
+	return strings.Join(i.FieldStrings(field), ", ")
+}
+
+// FieldStrings возвращает значения поля-массива (метки, компоненты, множественный выбор) в виде строк
+func (i JiraIssue) FieldStrings(field string) []string {
+	var v interface{}
+	if !i.decodeField(field, &v) {
+		return nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		items = []interface{}{v}
+	}
+	var result []string
+	for _, item := range items {
+		if s := jsonValueString(item); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (i JiraIssue) FieldNumber(field string) (float64, bool) {
+	var v interface{}
+	if !i.decodeField(field, &v) {
+		return 0, false
+	}
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// FieldTime разбирает поля-даты Jira: "2006-01-02" и "2006-01-02T15:04:05.000-0700"
+func (i JiraIssue) FieldTime(field string) (time.Time, bool) {
+	s := i.FieldString(field)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (i JiraIssue) fieldSeconds(field string) (time.Duration, bool) {
+	n, ok := i.FieldNumber(field)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func (i JiraIssue) decodeField(field string, v interface{}) bool {
+	raw, ok := i.Fields[field]
+	if !ok || len(raw) == 0 || string(raw) == "null" {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return fmt.Sprintf("%g", val)
+	case bool:
+		return fmt.Sprintf("%t", val)
+	case map[string]interface{}:
+		for _, key := range []string{"value", "name", "displayName", "key", "id"} {
+			if s, ok := val[key].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// --- Поля Jira ---
+
+// GetFieldIDs возвращает соответствие имени поля (в нижнем регистре) и ID поля в Jira
+func (c *JiraClient) GetFieldIDs() (map[string]string, error) {
+	if c.fieldIDs != nil {
+		return c.fieldIDs, nil
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/rest/api/latest/field", c.BaseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка ответа: %d", resp.StatusCode)
+	}
+
+	var fields []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	c.fieldIDs = make(map[string]string, len(fields)*2)
+	for _, f := range fields {
+		c.fieldIDs[strings.ToLower(f.Name)] = f.ID
+	}
+	// ID имеют приоритет над совпадающими именами
+	for _, f := range fields {
+		c.fieldIDs[strings.ToLower(f.ID)] = f.ID
+	}
+	return c.fieldIDs, nil
+}
+
+// ResolveFieldID возвращает ID поля по его ID или имени, например "Story Points" -> customfield_10002
+func (c *JiraClient) ResolveFieldID(field string) (string, error) {
+	if strings.HasPrefix(field, "customfield_") {
+		return field, nil
+	}
+	for _, f := range append([]string{"summary"}, defaultIssueFields...) {
+		if f == field {
+			return field, nil
+		}
+	}
+
+	ids, err := c.GetFieldIDs()
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения списка полей: %w", err)
+	}
+	id, ok := ids[strings.ToLower(field)]
+	if !ok {
+		return "", fmt.Errorf("поле '%s' не найдено в Jira", field)
+	}
+	return id, nil
+}
+
+type fieldRef struct {
+	name  string
+	field *string
+}
+
+// structureFieldRefs возвращает заданные в настройках структуры ссылки на отдельные поля Jira
+func structureFieldRefs(structure *StructureConfig) []fieldRef {
+	var refs []fieldRef
+	add := func(name string, field *string) {
+		if *field != "" {
+			refs = append(refs, fieldRef{name: name, field: field})
+		}
+	}
+	add("поле веса задачи", &structure.Anneal.WeightField)
+	add("поле срока", &structure.Deadlines.Field)
+	add("поле story points", &structure.Durations.StoryPointsField)
+	add("поле количества слотов", &structure.Allocation.SlotsField)
+	add("поле доли загрузки", &structure.Allocation.FractionField)
+	if isAffinityFieldKey(structure.Affinity.Key) {
+		add("поле проекта задачи", &structure.Affinity.Key)
+	}
+	for i := range structure.Rules {
+		add(fmt.Sprintf("поле правила %d", i+1), &structure.Rules[i].Match.Field)
+	}
+	for i := range structure.WIPLimits {
+		if isWIPFieldKey(structure.WIPLimits[i].Key) {
+			add("поле ограничения одновременно выполняемых задач", &structure.WIPLimits[i].Key)
+		}
+	}
+	for i := range structure.Pools {
+		add(fmt.Sprintf("поле пула %s", structure.Pools[i].Name), &structure.Pools[i].Match.Field)
+	}
+	return refs
+}
+
+// resolveStructureFields заменяет имена полей в настройках структуры на их ID
+func resolveStructureFields(client *JiraClient, structure *StructureConfig) error {
+	// Пулы, ограничения и правила копируются, чтобы не менять общие настройки из файла
+	structure.Pools = append([]PoolConfig(nil), structure.Pools...)
+	structure.WIPLimits = append([]WIPLimitConfig(nil), structure.WIPLimits...)
+	structure.Rules = append([]IssueRuleConfig(nil), structure.Rules...)
+
+	fields := make([]string, 0, len(structure.Fields))
+	for _, f := range structure.Fields {
+		id, err := client.ResolveFieldID(f)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, id)
+	}
+	structure.Fields = fields
+
+	for _, ref := range structureFieldRefs(structure) {
+		id, err := client.ResolveFieldID(*ref.field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ref.name, err)
+		}
+		*ref.field = id
+	}
+
+	keys := make([]SortKeyConfig, 0, len(structure.Sort))
+	for _, k := range structure.Sort {
+		if !isInternalSortKey(k.Key) {
+			id, err := client.ResolveFieldID(k.Key)
+			if err != nil {
+				return fmt.Errorf("ключ сортировки: %w", err)
+			}
+			k.Key = id
+		}
+		keys = append(keys, k)
+	}
+	structure.Sort = keys
+	return nil
+}
+
+// issueFields возвращает поля, которые нужно запросить для задач структуры
+func issueFields(structure StructureConfig) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	extra := sortFields(structure.Sort)
+	for _, ref := range structureFieldRefs(&structure) {
+		extra = append(extra, *ref.field)
+	}
+	for _, group := range [][]string{defaultIssueFields, structure.Fields, extra} {
+		for _, f := range group {
+			if !seen[f] {
+				seen[f] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
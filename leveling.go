@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// LevelingTask — задача структуры, для которой рассчитывается задержка выравнивания
+type LevelingTask struct {
+	Issue      JiraIssue
+	Row        ForestRow
+	RowIDs     []int // строки, которым выставляется задержка, первая — основная
+	Attributes *StructureRowAttributes
+	Position   int // позиция задачи в исходном списке: JQL или структуры
+
+	Duration       time.Duration // работа, которую нужно разместить в слоте
+	DurationSource string        // источник длительности, пустой — длительность не определена
+
+	Summary    bool   // строка-родитель, длительность которой складывается из дочерних строк
+	Milestone  bool   // веха: не занимает слот и ставится на окончание предшественников
+	Pinned     bool   // задача занимает слот в своих текущих датах, задержка не меняется
+	InProgress bool   // задача в работе, Duration — оставшаяся работа
+	Group      int    // строка-родитель, задачи которой выравниваются в одном слоте подряд, 0 — без группы
+	Pool       string // пул, в слотах которого выравнивается задача
+	ForcedPool string // пул, заданный правилом, пустой — по правилам пулов
+	ForcedSlot *int   // слот пула, заданный правилом
+
+	Width    int        // количество слотов, в которых задача выполняется одновременно, 0 — один слот
+	Fraction float64    // доля загрузки слота, 0 — полная загрузка
+	Context  string     // проект задачи для учета переключения слотов, пустой — не учитывается
+	Limits   []WIPLimit // ограничения одновременно выполняемых задач эпика, проекта или компонента
+
+	Deadline time.Time     // срок, пустой — срока нет
+	MaxShift time.Duration // наибольший сдвиг начала за запуск, 0 — без ограничения
+	Buffer   time.Duration // время после окончания задачи, в течение которого слот остается занят
+	Horizon  string        // действие с задачей за горизонтом планирования, пустое — задача в пределах горизонта
+
+	Predecessors []*LevelingTask // задачи выше вехи под тем же родителем
+
+	LevelingDelay time.Duration
+	Finish        time.Duration // смещение окончания задачи от начала проекта
+	Slot          int
+	PlacementErr  error // ошибка постановки в слоты, задержка такой задачи не записывается
+	ShiftLimited  bool  // задача сдвигалась больше max_shift и поставлена на наибольший допустимый сдвиг
+}
+
+// Demand возвращает работу задачи для постановки в слоты
+func (t *LevelingTask) Demand(timeline Timeline) Demand {
+	dm := Demand{Duration: t.Duration, Width: t.Width, Fraction: t.Fraction, Context: t.Context, Limits: t.Limits, Buffer: t.Buffer}
+	if t.MaxShift > 0 {
+		if current, ok := currentStart(t, timeline); ok {
+			dm.NotBefore = current - t.MaxShift
+		}
+	}
+	return dm
+}
+
+// Pin закрепляет задачу в текущих датах. Задача без даты начала в Gantt не закрепляется.
+func (t *LevelingTask) Pin() bool {
+	if t.Attributes == nil || t.Attributes.Start.IsZero() {
+		log.Printf("[WARNING] У задачи %s нет даты начала в Gantt, задача не будет закреплена\n", t.Issue.Key)
+		return false
+	}
+	t.Pinned = true
+	return true
+}
+
+// HasManualDates сообщает, что в Gantt для задачи вручную выставлены дата начала или окончания
+func (t *LevelingTask) HasManualDates() bool {
+	return !t.Attributes.ManualStart.IsZero() || !t.Attributes.ManualFinish.IsZero()
+}
+
+// collectTasks сопоставляет задачи строкам структуры и получает их атрибуты из Gantt
+func collectTasks(client *JiraClient, structure StructureConfig, forest *Forest, issues []JiraIssue, issueRows map[string][]int) ([]*LevelingTask, error) {
+	var tasks []*LevelingTask
+	var rowIDs []int
+	for i, issue := range issues {
+		rows, ok := issueRows[issue.ID]
+		if !ok {
+			log.Printf("[WARNING] Задачи %s (%s) нет в структуре %d. Задача будет пропущена.\n", issue.Key, issue.ID, structure.ID)
+			continue
+		}
+		row, _ := forest.Row(rows[0])
+		task := &LevelingTask{
+			Issue:    issue,
+			Row:      row,
+			RowIDs:   rows,
+			Position: i,
+			Slot:     -1,
+		}
+		if structure.Hierarchy.SkipParents && forest.HasChildren(row.ID) {
+			task.Summary = true
+		}
+		if structure.Hierarchy.KeepChildrenTogether && row.Parent != 0 {
+			task.Group = row.Parent
+		}
+		tasks = append(tasks, task)
+		rowIDs = append(rowIDs, row.ID)
+	}
+
+	if len(rowIDs) == 0 {
+		return tasks, nil
+	}
+
+	log.Printf("Получаем текущие атрибуты из Gantt для %d задач\n", len(rowIDs))
+	var extra []string
+	if structure.Progress.GanttAttribute != "" {
+		extra = append(extra, structure.Progress.GanttAttribute)
+	}
+	if structure.Milestones.GanttAttribute != "" && structure.Milestones.GanttAttribute != structure.Progress.GanttAttribute {
+		extra = append(extra, structure.Milestones.GanttAttribute)
+	}
+	attributes, err := client.GetRowsAttributes(structure.ID, rowIDs, extra)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения атрибутов: %v", err)
+	}
+	for _, task := range tasks {
+		task.Attributes = attributes[task.Row.ID]
+	}
+
+	return tasks, nil
+}
+
+// groupTasks переставляет задачи одной группы подряд, на место первой задачи группы
+func groupTasks(tasks []*LevelingTask) []*LevelingTask {
+	var order []int
+	groups := make(map[int][]*LevelingTask)
+	result := make([]*LevelingTask, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Group == 0 {
+			// Задача без группы занимает свое место в порядке
+			order = append(order, -len(result)-1)
+			result = append(result, task)
+			continue
+		}
+		if _, ok := groups[task.Group]; !ok {
+			order = append(order, task.Group)
+		}
+		groups[task.Group] = append(groups[task.Group], task)
+	}
+
+	grouped := make([]*LevelingTask, 0, len(tasks))
+	for _, key := range order {
+		if key < 0 {
+			grouped = append(grouped, result[-key-1])
+			continue
+		}
+		grouped = append(grouped, groups[key]...)
+	}
+	return grouped
+}
+
+// scheduleTasks рассчитывает задержки выравнивания задач в порядке списка
+// Если задача сдвигается позже, чем позволяет max_shift, она ставится на наибольший допустимый сдвиг
+// так же, как закрепленная, и расчет повторяется, чтобы остальные задачи обошли ее.
+func scheduleTasks(tasks []*LevelingTask, slots *Slots, timeline Timeline) {
+	initial := slots.Clone()
+	limited := make(map[*LevelingTask]time.Duration)
+	for attempt := 0; placeTasks(tasks, slots, timeline, limited) && attempt < len(tasks); attempt++ {
+		slots.restore(initial)
+	}
+}
+
+// placeTasks ставит задачи в слоты: сначала закрепленные и задачи с ограниченным сдвигом из limited, затем
+// остальные по порядку. Возвращает true, если в limited добавлены задачи, сдвинувшиеся больше max_shift.
+func placeTasks(tasks []*LevelingTask, slots *Slots, timeline Timeline, limited map[*LevelingTask]time.Duration) bool {
+	// Закрепленные задачи остаются в своих датах независимо от порядка, поэтому занимают слоты до остальных задач
+	for _, task := range tasks {
+		task.PlacementErr = nil
+		_, task.ShiftLimited = limited[task]
+		switch {
+		case task.Summary:
+		case task.Pinned:
+			start := timeline.OffsetForDate(dateIdFromTime(task.Attributes.Start))
+			reserveTask(task, slots, dateIdFromTime(task.Attributes.Start), start)
+		case task.ShiftLimited:
+			start := limited[task]
+			task.LevelingDelay = start
+			dateId, _, err := timeline.DateForOffset(start)
+			if err != nil {
+				dateId = timeline.StartDateId
+			}
+			reserveTask(task, slots, dateId, start)
+		}
+	}
+
+	added := false
+	groupSlots := make(map[int]int)
+	for _, task := range tasks {
+		attributes := task.Attributes
+
+		// Строки-родители не выравниваются: их длительность складывается из дочерних строк
+		if task.Summary || task.Pinned || task.ShiftLimited {
+			continue
+		}
+
+		slot, grouped := groupSlots[task.Group]
+		grouped = grouped && task.Group != 0
+		// Слот из правила важнее слота группы, следующие задачи группы ставятся за задачей (см. warnSplitGroups)
+		if task.ForcedSlot != nil && *task.ForcedSlot < slots.Len() {
+			slot, grouped = *task.ForcedSlot, true
+		}
+
+		// Задача в работе продолжается с даты начала выравнивания, даже если задач в работе больше, чем слотов
+		if task.InProgress && !task.HasManualDates() {
+			var err error
+			if !grouped {
+				var dateId int
+				if dateId, _, err = timeline.DateForOffset(slots.Start); err == nil {
+					slot, err = slots.FindSlot(dateId)
+				}
+			}
+			if err == nil {
+				task.LevelingDelay, task.Finish, err = slots.Continue(slot, task.Demand(timeline))
+			}
+			task.PlacementErr = err
+			if err != nil {
+				task.LevelingDelay, task.Finish = 0, task.Duration
+			}
+			task.Slot = slot
+			if task.Group != 0 {
+				groupSlots[task.Group] = slot
+			}
+			continue
+		}
+
+		// Если для задачи в ручную выставлены дата начала или окончания, выставление задержки не нужно.
+		// Выбираем наименьший слот и выставляем в него дату смещение рассчитанное
+		// TODO: обработать корнер кейсы. Тут сделано допущение, что JQL возвращает задачи отсортированные по дате завершения
+		if task.HasManualDates() {
+			if !grouped {
+				var err error
+				if slot, err = slots.FindSlot(dateIdFromTime(attributes.Start)); err != nil {
+					task.PlacementErr = err
+					continue
+				}
+			}
+			task.Finish = timeline.Calendar.GetWorkingDurationBetween(timeline.StartDateId, dateIdFromTime(attributes.Start)) + task.Duration
+			slots.SetDelay(slot, slots.bufferEnd(slot, task.Finish, Demand{Buffer: task.Buffer}))
+			slots.SetContext(slot, task.Context)
+			slots.WIP.Add(task.Limits, task.Finish-task.Duration, task.Finish)
+		} else {
+			var err error
+			if grouped {
+				task.LevelingDelay, task.Finish, err = slots.AddToSlot(slot, task.Demand(timeline))
+			} else {
+				var allocated []int
+				task.LevelingDelay, task.Finish, allocated, err = slots.Allocate(task.Demand(timeline))
+				if err == nil {
+					slot = allocated[0]
+				}
+			}
+			task.PlacementErr = err
+			if err != nil {
+				task.LevelingDelay, task.Finish = 0, task.Duration
+			} else if latest, ok := latestStart(task, timeline, slots.Start); ok && task.LevelingDelay > latest {
+				limited[task] = latest
+				added = true
+			}
+		}
+
+		task.Slot = slot
+		if task.Group != 0 {
+			groupSlots[task.Group] = slot
+		}
+	}
+	return added
+}
+
+// reserveTask занимает наименее загруженный слот, открытый в день начала задачи, от start до окончания задачи
+// и буфера после него
+func reserveTask(task *LevelingTask, slots *Slots, dateId int, start time.Duration) {
+	task.Finish = start + task.Duration
+	slot, err := slots.FindSlot(dateId)
+	if err == nil {
+		slots.Reserve(slot, slots.bufferEnd(slot, task.Finish, Demand{Buffer: task.Buffer}))
+		slots.SetContext(slot, task.Context)
+		task.Slot = slot
+	}
+	slots.WIP.Add(task.Limits, start, task.Finish)
+}
+
+func logSchedule(tasks []*LevelingTask) {
+	for _, task := range tasks {
+		switch {
+		case task.Summary:
+			log.Printf("Задача %s — строка-родитель, задержка выравнивания будет сброшена\n", task.Issue.Key)
+		case task.PlacementErr != nil:
+			log.Printf("[WARNING] Задачу %s не удалось поставить в слоты: %v, задержка выравнивания не записывается\n", task.Issue.Key, task.PlacementErr)
+		case task.Pinned:
+			log.Printf("Задача %s закреплена, задержка выравнивания не меняется\n", task.Issue.Key)
+		case task.Milestone:
+			log.Printf("Веха %s, задержка выравнивания: %s\n", task.Issue.Key, task.LevelingDelay)
+		case task.Horizon == HorizonKeep:
+			log.Printf("Задача %s за горизонтом планирования, задержка выравнивания не меняется\n", task.Issue.Key)
+		case task.Horizon != "":
+			log.Printf("Задача %s за горизонтом планирования, задержка выравнивания: %s\n", task.Issue.Key, task.LevelingDelay)
+		default:
+			log.Printf("Задержка выравнивания для задачи %s: %s\n", task.Issue.Key, task.LevelingDelay)
+		}
+	}
+}
+
+// applyLevelingDelays записывает рассчитанные задержки во все строки задач
+func applyLevelingDelays(client *JiraClient, structureID, ganttID int, tasks []*LevelingTask) error {
+	for _, task := range tasks {
+		if task.Pinned || task.Horizon == HorizonKeep || task.PlacementErr != nil {
+			continue
+		}
+		log.Printf("Выставляем задержку выравнивания %s для задачи %s\n", task.LevelingDelay, task.Issue.Key)
+		for _, rowID := range task.RowIDs {
+			// Версию диаграммы получаем непосредственно перед изменением, так как она меняется после каждой записи
+			attributes, err := client.GetRowAttributes(structureID, rowID)
+			if err != nil {
+				return fmt.Errorf("ошибка получения атрибутов: %v", err)
+			}
+			err = client.UpdateLevelingDelay(ganttID, rowID, task.LevelingDelay, attributes.Signature, attributes.Version)
+			if err != nil {
+				return fmt.Errorf("ошибка обновления задержки выравнивания задачи %s: %w", task.Issue.Key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleTasksReservesPinnedFirst(t *testing.T) {
+	timeline := testTimeline()
+	free := testTask(t, "T-1", 16*time.Hour, 20250131)
+	pinned := testTask(t, "T-2", 16*time.Hour, 20250131)
+	pinned.Pinned = true
+	pinned.Attributes.Start, _ = parseDateId(20250106)
+
+	scheduleTasks([]*LevelingTask{free, pinned}, NewSlots(timeline, 1, 0), timeline)
+
+	if pinned.Finish != 16*time.Hour {
+		t.Errorf("закрепленная задача заканчивается %s, ожидается 16h", pinned.Finish)
+	}
+	if free.LevelingDelay != 16*time.Hour {
+		t.Errorf("задача перед закрепленной начинается %s, ожидается после нее — 16h", free.LevelingDelay)
+	}
+}
+
+func TestResolveStabilityFreezesPastStarts(t *testing.T) {
+	timeline := testTimeline()
+	past := testTask(t, "T-1", 8*time.Hour, 20250131)
+	past.Attributes.Start, _ = parseDateId(20250103)
+	soon := testTask(t, "T-2", 8*time.Hour, 20250131)
+	soon.Attributes.Start, _ = parseDateId(20250108)
+	later := testTask(t, "T-3", 8*time.Hour, 20250131)
+	later.Attributes.Start, _ = parseDateId(20250120)
+	running := testTask(t, "T-4", 8*time.Hour, 20250131)
+	running.Attributes.Start, _ = parseDateId(20250103)
+	running.InProgress = true
+
+	resolveStability([]*LevelingTask{past, soon, later, running}, StabilityConfig{FreezeDays: 5}, 0, timeline, 20250106)
+
+	if !past.Pinned || !soon.Pinned {
+		t.Error("задачи с началом в прошлом и в пределах заморозки должны закрепляться")
+	}
+	if later.Pinned || running.Pinned {
+		t.Error("задачи после заморозки и задачи в работе не должны закрепляться")
+	}
+}
+
+func TestScheduleTasksHoldsMaxShift(t *testing.T) {
+	timeline := testTimeline()
+	first := testTask(t, "T-1", 40*time.Hour, 20250131)
+	limited := testTask(t, "T-2", 8*time.Hour, 20250131)
+	limited.Attributes.Start, _ = parseDateId(20250106)
+	limited.MaxShift = 8 * time.Hour
+
+	scheduleTasks([]*LevelingTask{first, limited}, NewSlots(timeline, 1, 0), timeline)
+
+	if !limited.ShiftLimited || limited.LevelingDelay != 8*time.Hour {
+		t.Fatalf("задача с max_shift начинается %s, ожидается наибольший допустимый сдвиг 8h", limited.LevelingDelay)
+	}
+	if first.LevelingDelay < limited.Finish && first.Finish > limited.LevelingDelay {
+		t.Errorf("задачи пересекаются в одном слоте: %s–%s и %s–%s", first.LevelingDelay, first.Finish, limited.LevelingDelay, limited.Finish)
+	}
+}
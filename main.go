@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
 func main() {
 	log.SetOutput(os.Stdout)
 
+	if len(os.Args) > 1 && os.Args[1] == "calendar" {
+		runCalendarCommand(os.Args[2:])
+		return
+	}
+
 	config := flag.String("c", "config.yml", "Путь к конфигурационному файлу YAML (если не указано — config.yml)")
 	structure := flag.String("s", "", "Название секции из 'structures' для выполнения (если не указано — выполняются все)")
 
@@ -29,108 +35,298 @@ func main() {
 
 	// Создаем клиента
 	client := NewJiraClient(cfg.Client)
+	shared, err := sharedStructures(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка настройки общих пулов: %v", err)
+	}
+
 	if structure != nil && *structure != "" {
-		if _, ok := cfg.Structures[*structure]; !ok {
+		structureCfg, ok := cfg.Structures[*structure]
+		if !ok {
 			log.Fatalf("В спикке структур нет настроек для '%s' в конфигурационном файле", *structure)
 		}
-		log.Printf("Выставляем задержки выравнивания для структуры '%s'\n", *structure)
-		err = calculateLeveling(client, cfg.Structures[*structure])
+		if usesSharedPools(structureCfg) {
+			// Остальные структуры с общими пулами нужны, чтобы учесть занятость общих слотов
+			log.Printf("Выставляем задержки выравнивания для структуры '%s' с учетом общих пулов\n", *structure)
+			err = calculateSharedLeveling(client, cfg, shared, map[string]bool{*structure: true})
+		} else {
+			log.Printf("Выставляем задержки выравнивания для структуры '%s'\n", *structure)
+			err = calculateLeveling(client, structureCfg)
+		}
 		if err != nil {
 			log.Fatalf("Не удалось выставить задержки для структуры '%s': %v", *structure, err)
 		}
 		return
 	}
 	for structureName, structureCfg := range cfg.Structures {
+		if usesSharedPools(structureCfg) {
+			continue
+		}
 		log.Printf("Выставляем задержки выравнивания для структуры '%s'\n", structureName)
 		err = calculateLeveling(client, structureCfg)
 		if err != nil {
 			log.Fatalf("Не удалось выставить задержки выравнивания для структуры '%s': %v", structureName, err)
 		}
 	}
+	if len(shared) > 0 {
+		log.Printf("Выставляем задержки выравнивания для структур с общими пулами: %s\n", strings.Join(shared, ", "))
+		write := make(map[string]bool, len(shared))
+		for _, name := range shared {
+			write[name] = true
+		}
+		if err := calculateSharedLeveling(client, cfg, shared, write); err != nil {
+			log.Fatalf("Не удалось выставить задержки выравнивания для структур с общими пулами: %v", err)
+		}
+	}
 }
 
-func calculateLeveling(client *JiraClient, structure StructureConfig) error {
+// loadGantt получает диаграмму Ганта структуры и накладывает на ее календарь переопределения из конфигурации
+func loadGantt(client *JiraClient, structure StructureConfig) (int, *GanttMeta, []CalendarOverride, error) {
 	log.Printf("Получаем информацию о Gantt-диограмме для структуры %d\n", structure.ID)
 	ganttID, err := client.GetGanttId(structure.ID)
 	if err != nil {
-		return fmt.Errorf("ошибка получения ID диаграммы Ганта: %v", err)
+		return 0, nil, nil, fmt.Errorf("ошибка получения ID диаграммы Ганта: %v", err)
 	}
 
 	gantt, err := client.GetGanttMeta(structure.ID, ganttID)
 	if err != nil {
-		return fmt.Errorf("ошибка получения информации о диаграмме Ганта: %v", err)
-	}
-
-	log.Printf("Получаем соответсвие issueID к rowID в структуре %d\n", structure.ID)
-	issueIDToRowID, err := client.GetForestMapping(structure.ID)
-	if err != nil {
-		return fmt.Errorf("ошибка получения соответсвия issueID к rowID: %v", err)
+		return 0, nil, nil, fmt.Errorf("ошибка получения информации о диаграмме Ганта: %v", err)
 	}
 
-	log.Printf("Получаем список задач по JQL: '%s'\n", structure.JQL)
-	issues, err := client.GetIssues(structure.JQL)
+	overrides, err := applyCalendarOverrides(&gantt.Calendar, structure.Calendar)
 	if err != nil {
-		return fmt.Errorf("ошибка получения списка зададач: %w", err)
+		return 0, nil, nil, fmt.Errorf("ошибка переопределения календаря: %w", err)
 	}
+	return ganttID, gantt, overrides, nil
+}
 
-	// Создаем слоты по количеству параллельных проектов
-	// Каждый слот будет хранить задержку от начала проекта в кол-ве рабочих часов
-	var slots Slots
+// levelingStart возвращает дату, с которой начинается выравнивание, и задержку этой даты от начала диаграммы
+func levelingStart(structure StructureConfig, gantt *GanttMeta) (int, time.Duration) {
 	todayId := structure.StartDateID
 	if todayId <= 0 {
 		todayId = dateIdFromTime(time.Now())
 	}
 	if gantt.StartDateId >= todayId {
-		// если дата начала проекта совпадает с текущей датой или в будущем, то задержки от начала проекта во всех слотах нет
-		slots = NewSlots(structure.ParallelProjects, 0)
-	} else {
-		// Если дата начала в прошлом, выставляем в каждом слоте задержку равную кол-ву рабочих часов между датой начала проекта и текущей
-		// Выравнивание задач начнется с текущей даты
-		slots = NewSlots(structure.ParallelProjects, gantt.Calendar.GetWorkingDurationBetween(gantt.StartDateId, todayId))
+		// если дата начала проекта совпадает с текущей датой или в будущем, выравнивание начинается с начала проекта
+		return gantt.StartDateId, 0
 	}
+	// Если дата начала в прошлом, задержка равна кол-ву рабочих часов между датой начала проекта и текущей
+	return todayId, gantt.Calendar.GetWorkingDurationBetween(gantt.StartDateId, todayId)
+}
 
-	for _, issue := range issues {
-		log.Printf("Рассчитываем задержку выравнивания для задачи %s\n", issue.Key)
-		rowID, ok := issueIDToRowID[issue.ID]
-		if !ok {
-			log.Printf("[WARNING] Задачи %s (%s) нет в структуре %d. Задача будет пропущена.\n", issue.Key, issue.ID, structure.ID)
-			continue
-		}
+func calculateLeveling(client *JiraClient, structure StructureConfig) error {
+	run, err := prepareLeveling(client, structure)
+	if err != nil {
+		return err
+	}
+	if err := run.level(); err != nil {
+		return fmt.Errorf("ошибка выравнивания: %w", err)
+	}
+	return run.apply(client)
+}
 
-		rowIDInt, err := parseInt(rowID)
-		if err != nil {
-			return fmt.Errorf("ошибка преобразования rowID в число: %w", err)
-		}
+// levelingRun — подготовленное к выравниванию состояние структуры: задачи распределены по пулам
+type levelingRun struct {
+	Structure     StructureConfig
+	GanttID       int
+	Timeline      Timeline
+	StartDateId   int           // дата, с которой начинается выравнивание
+	ProjectBuffer time.Duration // фиксированная часть буфера проекта
+	Horizon       time.Duration // горизонт планирования от даты начала выравнивания
+	Strategy      LevelingStrategy
+	Pools         []*Pool
+	Summaries     []*LevelingTask // строки-родители, которые не занимают слоты
+	Milestones    []*LevelingTask // вехи, которые ставятся после выравнивания
+	Tasks         []*LevelingTask // выровненные задачи
+}
 
-		log.Printf("Получаем текущие атрибуты из Gantt для задачи %s\n", issue.Key)
-		attributes, err := client.GetRowAttributes(structure.ID, rowIDInt)
-		if err != nil {
-			return fmt.Errorf("ошибка получения атрибутов: %v", err)
+// prepareLeveling загружает диаграмму и задачи структуры, рассчитывает длительности, сроки, порядок задач
+// и распределяет их по пулам
+func prepareLeveling(client *JiraClient, structure StructureConfig) (*levelingRun, error) {
+	if structure.Deadlines.EarliestDeadlineFirst {
+		if !structure.Deadlines.Enabled {
+			return nil, fmt.Errorf("earliest_deadline_first требует deadlines.enabled")
 		}
+		structure.Sort = append([]SortKeyConfig{{Key: SortKeyDeadline}}, structure.Sort...)
+	}
+	if err := validateSortKeys(structure.Sort); err != nil {
+		return nil, err
+	}
+	if err := validateProgressConfig(structure.Progress); err != nil {
+		return nil, err
+	}
+	if err := validatePoolsConfig(structure.Pools); err != nil {
+		return nil, err
+	}
+	if err := validateAllocationConfig(structure.Allocation); err != nil {
+		return nil, err
+	}
+	if err := validateWIPLimits(structure.WIPLimits); err != nil {
+		return nil, err
+	}
+	if err := validateMilestonesConfig(structure.Milestones); err != nil {
+		return nil, err
+	}
+	switchCost, maxWait, err := parseAffinityConfig(structure.Affinity)
+	if err != nil {
+		return nil, err
+	}
+	maxShift, err := parseStabilityConfig(structure.Stability)
+	if err != nil {
+		return nil, err
+	}
+	afterTaskBuffer, projectBuffer, err := parseBuffersConfig(structure.Buffers)
+	if err != nil {
+		return nil, err
+	}
+	horizon, err := parseHorizonConfig(structure.Horizon)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveStructureFields(client, &structure); err != nil {
+		return nil, err
+	}
+	// Стратегия создается после разрешения полей: она может читать поля задач по ID, например вес задачи
+	strategy, err := newStrategy(structure)
+	if err != nil {
+		return nil, err
+	}
 
-		// Определяем задержку для задачи относительно начала проекта
-		var levelingDelay time.Duration
-
-		// Если для задачи в ручную выставлены дата начала или окончания, выставление задержки не нужно.
-		// Выбираем наименьший слот и выставляем в него дату смещение рассчитанное
-		// TODO: обработать корнер кейсы. Тут сделано допущение, что JQL возвращает задачи отсортированные по дате завершения
-		if !attributes.ManualStart.IsZero() || !attributes.ManualFinish.IsZero() {
-			slot, _ := slots.FindSlot()
-			slots.SetDelay(
-				slot,
-				gantt.Calendar.GetWorkingDurationBetween(gantt.StartDateId, dateIdFromTime(attributes.Start))+attributes.Duration,
-			)
-		} else {
-			levelingDelay, _ = slots.GetLevelingDelayAndAdd(attributes.Duration)
-		}
+	ganttID, gantt, overrides, err := loadGantt(client, structure)
+	if err != nil {
+		return nil, err
+	}
+	logCalendarOverrides(overrides)
 
-		log.Printf("Выставляем задержку выравнивания %s для задачи %s\n", levelingDelay, issue.Key)
+	log.Printf("Получаем строки структуры %d\n", structure.ID)
+	forest, err := client.GetForest(structure.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения строк структуры: %v", err)
+	}
+	log.Printf("Строк в структуре: %d (%s)\n", len(forest.Rows), forest.TypeSummary())
 
-		err = client.UpdateLevelingDelay(ganttID, rowIDInt, levelingDelay, attributes.Signature, attributes.Version)
-		if err != nil {
-			log.Fatalf("ошибка обновления задержки выравнивания: %v", err)
+	issues, err := getLevelingIssues(client, structure, forest)
+	if err != nil {
+		return nil, err
+	}
+
+	issueRows, err := selectIssueRows(forest, issues, structure.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := loadResources(structure)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки ресурсов: %w", err)
+	}
+
+	// Создаем слоты пулов, без пулов — по количеству параллельных проектов
+	// Каждый слот будет хранить задержку от начала проекта в кол-ве рабочих часов
+	// Выравнивание задач начнется с текущей даты
+	timeline := Timeline{Calendar: &gantt.Calendar, StartDateId: gantt.StartDateId}
+	startDateId, initialDelay := levelingStart(structure, gantt)
+	if err := validateHorizonStart(structure.Horizon, startDateId); err != nil {
+		return nil, err
+	}
+	pools, err := buildPools(structure, resources, timeline, initialDelay)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateIssueRules(structure.Rules, pools); err != nil {
+		return nil, err
+	}
+	// Ограничения одновременно выполняемых задач действуют во всех пулах структуры
+	var wip *WIPTracker
+	if len(structure.WIPLimits) > 0 {
+		wip = NewWIPTracker()
+	}
+	for _, pool := range pools {
+		if pool.Slots != nil {
+			pool.Slots.SwitchCost, pool.Slots.MaxWait = switchCost, maxWait
+			pool.Slots.WIP = wip
+			pool.Slots.RoundStart = structure.Buffers.RoundStart
 		}
 	}
+	if len(resources) > 0 {
+		var total int
+		for _, pool := range pools {
+			if pool.Slots != nil {
+				total += pool.Slots.Len()
+			}
+		}
+		log.Printf("Слотов: %d, из них с календарем ресурса: %d\n", total, len(resources))
+	}
+
+	tasks, err := collectTasks(client, structure, forest, issues, issueRows)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveDurations(tasks, structure.Durations); err != nil {
+		return nil, err
+	}
 
+	tasks = resolveMilestones(tasks, structure.Milestones)
+	resolveAllocation(tasks, structure.Allocation)
+	resolveContexts(tasks, structure.Affinity)
+	resolveWIPLimits(tasks, structure.WIPLimits)
+	resolveBuffers(tasks, structure.Buffers, afterTaskBuffer)
+	resolveDeadlines(tasks, structure.Deadlines)
+
+	sortTasks(tasks, structure.Sort)
+	tasks = applyProgress(tasks, structure.Progress)
+	tasks, err = applyIssueRules(client, tasks, structure.Rules)
+	if err != nil {
+		return nil, err
+	}
+	resolveStability(tasks, structure.Stability, maxShift, timeline, startDateId)
+	if structure.Hierarchy.KeepChildrenTogether {
+		tasks = groupTasks(tasks)
+	}
+	tasks, milestones := splitMilestones(tasks, forest)
+
+	return &levelingRun{
+		Structure:     structure,
+		GanttID:       ganttID,
+		Timeline:      timeline,
+		StartDateId:   startDateId,
+		ProjectBuffer: projectBuffer,
+		Horizon:       horizon,
+		Strategy:      strategy,
+		Pools:         pools,
+		Summaries:     assignPools(tasks, pools),
+		Milestones:    milestones,
+	}, nil
+}
+
+// level выравнивает задачи в собственных пулах структуры, общие пулы выравниваются отдельно
+func (r *levelingRun) level() error {
+	tasks, err := levelPools(r.Strategy, r.Pools, r.Timeline)
+	if err != nil {
+		return err
+	}
+	r.Tasks = append(r.Tasks, tasks...)
 	return nil
 }
+
+// apply выводит результат выравнивания и записывает задержки в Gantt
+func (r *levelingRun) apply(client *JiraClient) error {
+	horizon, limited := horizonOffset(r.Structure.Horizon, r.Horizon, r.Timeline, r.StartDateId)
+	var beyond int
+	if limited {
+		beyond = applyHorizon(r.Tasks, r.Structure.Horizon, horizon, r.Timeline)
+	}
+	reportShiftLimits(r.Tasks)
+	// Вехи ставятся по тем датам предшественников, которые будут записаны в Gantt
+	anchorMilestones(r.Milestones, max(0, r.Timeline.OffsetForDate(r.StartDateId)))
+	if limited {
+		beyond += applyHorizon(r.Milestones, r.Structure.Horizon, horizon, r.Timeline)
+		reportHorizon(r.Structure.Horizon, horizon, r.Timeline, beyond)
+	}
+	r.Tasks = append(r.Tasks, r.Milestones...)
+	tasks := append(r.Tasks, r.Summaries...)
+	logSchedule(tasks)
+	reportLateness(tasks, r.Timeline)
+	reportProjectBuffer(tasks, r.Timeline, r.StartDateId, r.Structure.Buffers, r.ProjectBuffer)
+
+	return applyLevelingDelays(client, r.Structure.ID, r.GanttID, tasks)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Действия с вехами
+const (
+	MilestoneAnchor = "anchor" // веха ставится на окончание предшествующих задач
+	MilestoneSkip   = "skip"   // веха не выравнивается, задержка не меняется
+)
+
+func validateMilestonesConfig(cfg MilestonesConfig) error {
+	if cfg.Action != "" && cfg.Action != MilestoneAnchor && cfg.Action != MilestoneSkip {
+		return fmt.Errorf("неизвестное действие с вехами: '%s'", cfg.Action)
+	}
+	return nil
+}
+
+// isMilestone сообщает, что задача — веха по типу задачи или атрибуту Gantt
+func isMilestone(task *LevelingTask, cfg MilestonesConfig) bool {
+	if len(cfg.IssueTypes) > 0 && containsAnyFold([]string{task.Issue.IssueType()}, cfg.IssueTypes) {
+		return true
+	}
+	if cfg.GanttAttribute == "" {
+		return false
+	}
+	if task.Attributes == nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(task.Attributes.Extra[cfg.GanttAttribute])) {
+	case "", "0", "false", "no", "нет":
+		return false
+	}
+	return true
+}
+
+// resolveMilestones находит вехи, вехи с действием skip исключаются из выравнивания. Задачи без длительности, которые не являются вехами,
+// выводятся с предупреждением.
+func resolveMilestones(tasks []*LevelingTask, cfg MilestonesConfig) []*LevelingTask {
+	var milestones []*LevelingTask
+	var skipped, zero int
+	result := tasks[:0]
+	for _, task := range tasks {
+		if task.Summary {
+			result = append(result, task)
+			continue
+		}
+		if isMilestone(task, cfg) {
+			task.Milestone = true
+			task.Duration = 0
+			if cfg.Action == MilestoneSkip {
+				log.Printf("Веха %s не выравнивается, задержка выравнивания не меняется\n", task.Issue.Key)
+				skipped++
+				continue
+			}
+			milestones = append(milestones, task)
+		} else if task.Duration == 0 {
+			zero++
+			log.Printf("[WARNING] Задача %s без длительности и не является вехой: проверьте оценку или длительность в Gantt\n", task.Issue.Key)
+		}
+		result = append(result, task)
+	}
+
+	if len(milestones)+skipped+zero > 0 {
+		log.Printf("Вех: %d, из них не выравнивается: %d, задач без длительности: %d\n", len(milestones)+skipped, skipped, zero)
+	}
+	return result
+}
+
+// isDescendant сообщает, что строка находится внутри ancestor, для 0 — в любой строке
+func isDescendant(forest *Forest, rowID, ancestor int) bool {
+	if ancestor == 0 {
+		return true
+	}
+	for row, ok := forest.Row(rowID); ok && row.Parent != 0; row, ok = forest.Row(row.Parent) {
+		if row.Parent == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMilestones отделяет вехи: они не занимают слоты и ставятся после выравнивания. Предшественники вехи —
+// задачи итогового списка выше нее в структуре под тем же родителем.
+func splitMilestones(tasks []*LevelingTask, forest *Forest) ([]*LevelingTask, []*LevelingTask) {
+	var rest, milestones []*LevelingTask
+	for _, task := range tasks {
+		if task.Milestone {
+			milestones = append(milestones, task)
+			continue
+		}
+		rest = append(rest, task)
+	}
+	for _, m := range milestones {
+		m.Predecessors = nil
+		for _, task := range tasks {
+			if task != m && task.Row.Index < m.Row.Index && isDescendant(forest, task.Row.ID, m.Row.Parent) {
+				m.Predecessors = append(m.Predecessors, task)
+			}
+		}
+	}
+	return rest, milestones
+}
+
+// anchorMilestones ставит вехи на наибольшее окончание предшественников, без предшественников —
+// на дату начала выравнивания. Вехи обрабатываются в порядке структуры, чтобы веха могла
+// следовать за предыдущей вехой.
+func anchorMilestones(milestones []*LevelingTask, start time.Duration) {
+	sort.SliceStable(milestones, func(i, j int) bool {
+		return milestones[i].Row.Index < milestones[j].Row.Index
+	})
+	for _, m := range milestones {
+		if m.Pinned || m.HasManualDates() {
+			continue
+		}
+		anchor := start
+		for _, task := range m.Predecessors {
+			if !task.Summary && task.PlacementErr == nil {
+				anchor = max(anchor, task.Finish)
+			}
+		}
+		m.LevelingDelay, m.Finish = anchor, anchor
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// StrategyAnneal оптимизирует порядок задач имитацией отжига, затем применяет жадную стратегию
+const StrategyAnneal = "anneal"
+
+func init() {
+	RegisterStrategy(StrategyAnneal, func(structure StructureConfig) (LevelingStrategy, error) {
+		cfg := structure.Anneal
+		if err := validateAnnealConfig(&cfg, structure.Deadlines); err != nil {
+			return nil, err
+		}
+		return annealStrategy{cfg: cfg}, nil
+	})
+}
+
+type annealStrategy struct {
+	cfg AnnealConfig
+}
+
+func (s annealStrategy) Level(input LevelingInput) ([]*LevelingTask, error) {
+	tasks := optimizeOrder(input.Tasks, input.Slots, input.Timeline, s.cfg)
+	scheduleTasks(tasks, input.Slots, input.Timeline)
+	return tasks, nil
+}
+
+// Целевые функции оптимизации
+const (
+	ObjectiveWeightedTardiness = "weighted_tardiness" // сумма опозданий в часах, умноженных на вес задачи
+	ObjectiveMakespan          = "makespan"           // окончание последней задачи
+)
+
+const (
+	defaultAnnealIterations = 20000
+	defaultAnnealTimeBudget = time.Minute
+)
+
+// validateAnnealConfig проверяет настройки оптимизации и приводит приоритеты в priority_weights к нижнему регистру
+func validateAnnealConfig(cfg *AnnealConfig, deadlines DeadlinesConfig) error {
+	switch cfg.Objective {
+	case "", ObjectiveWeightedTardiness:
+		if !deadlines.Enabled {
+			return fmt.Errorf("целевая функция %s требует deadlines.enabled", ObjectiveWeightedTardiness)
+		}
+	case ObjectiveMakespan:
+	default:
+		return fmt.Errorf("неизвестная целевая функция: '%s'", cfg.Objective)
+	}
+	if cfg.TimeBudget != "" {
+		if _, err := time.ParseDuration(cfg.TimeBudget); err != nil {
+			return fmt.Errorf("некорректный time_budget: %w", err)
+		}
+	}
+	if len(cfg.PriorityWeights) > 0 {
+		weights := make(map[string]float64, len(cfg.PriorityWeights))
+		for priority, w := range cfg.PriorityWeights {
+			key := strings.ToLower(priority)
+			if _, ok := weights[key]; ok {
+				return fmt.Errorf("приоритет '%s' указан в priority_weights несколько раз", priority)
+			}
+			weights[key] = w
+		}
+		cfg.PriorityWeights = weights
+	}
+	return nil
+}
+
+// annealer ищет порядок задач, при котором жадная стратегия дает наименьшее значение целевой функции
+type annealer struct {
+	cfg       AnnealConfig
+	slots     *Slots
+	timeline  Timeline
+	deadlines map[*LevelingTask]time.Duration
+	weights   map[*LevelingTask]float64
+}
+
+// taskUnit — задачи, которые переставляются только вместе: задача или группа дочерних задач
+type taskUnit []*LevelingTask
+
+// movable сообщает, можно ли переставлять задачу: закрепленные задачи, задачи в работе,
+// строки-родители и задачи с ручными датами остаются на своих местах
+func (u taskUnit) movable() bool {
+	for _, task := range u {
+		if task.Summary || task.Pinned || task.InProgress || task.HasManualDates() {
+			return false
+		}
+	}
+	return true
+}
+
+// optimizeOrder переставляет задачи так, чтобы минимизировать целевую функцию, и выводит сравнение с исходным порядком
+func optimizeOrder(tasks []*LevelingTask, slots *Slots, timeline Timeline, cfg AnnealConfig) []*LevelingTask {
+	a := &annealer{
+		cfg:       cfg,
+		slots:     slots,
+		timeline:  timeline,
+		deadlines: make(map[*LevelingTask]time.Duration),
+		weights:   make(map[*LevelingTask]float64),
+	}
+	for _, task := range tasks {
+		a.weights[task] = taskWeight(task, cfg)
+		if !task.Deadline.IsZero() {
+			// Срок — конец рабочего дня
+			next, err := nextDateId(dateIdFromTime(task.Deadline))
+			if err == nil {
+				a.deadlines[task] = timeline.OffsetForDate(next)
+			}
+		}
+	}
+
+	// Разбиваем список на неделимые части, группы дочерних задач идут подряд
+	var units []taskUnit
+	for _, task := range tasks {
+		n := len(units)
+		if n > 0 && task.Group != 0 && units[n-1][0].Group == task.Group {
+			units[n-1] = append(units[n-1], task)
+			continue
+		}
+		units = append(units, taskUnit{task})
+	}
+	var positions []int
+	for i, u := range units {
+		if u.movable() {
+			positions = append(positions, i)
+		}
+	}
+
+	started := time.Now()
+	greedyCost := a.cost(units)
+	if len(positions) < 2 {
+		log.Printf("Оптимизация порядка не выполняется: переставлять нечего\n")
+		return tasks
+	}
+	if greedyCost == 0 {
+		log.Printf("Оптимизация порядка не выполняется: исходный порядок уже оптимален (%s 0)\n", a.objective())
+		return tasks
+	}
+
+	iterations := cfg.Iterations
+	if iterations <= 0 {
+		iterations = defaultAnnealIterations
+	}
+	budget := defaultAnnealTimeBudget
+	if cfg.TimeBudget != "" {
+		budget, _ = time.ParseDuration(cfg.TimeBudget)
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	current := append([]taskUnit(nil), units...)
+	currentCost := greedyCost
+	best := append([]taskUnit(nil), units...)
+	bestCost := greedyCost
+	t0 := math.Max(greedyCost*0.05, 1)
+
+	done := 0
+	for ; done < iterations && bestCost > 0; done++ {
+		if budget > 0 && done%64 == 0 && time.Since(started) > budget {
+			break
+		}
+		temperature := t0 * math.Pow(0.001, float64(done)/float64(iterations))
+
+		candidate := append([]taskUnit(nil), current...)
+		i := positions[rng.Intn(len(positions))]
+		j := positions[rng.Intn(len(positions))]
+		if i == j {
+			continue
+		}
+		if rng.Intn(2) == 0 {
+			candidate[i], candidate[j] = candidate[j], candidate[i]
+		} else {
+			moveUnit(candidate, positions, i, j)
+		}
+
+		c := a.cost(candidate)
+		if c <= currentCost || rng.Float64() < math.Exp((currentCost-c)/temperature) {
+			current, currentCost = candidate, c
+			if c < bestCost {
+				best = append(best[:0], candidate...)
+				bestCost = c
+			}
+		}
+	}
+
+	improvement := 0.0
+	if greedyCost > 0 {
+		improvement = (greedyCost - bestCost) / greedyCost * 100
+	}
+	log.Printf("Оптимизация порядка (%s): исходный порядок %.1f, оптимизированный %.1f, улучшение %.1f%% (%d итераций за %s)\n",
+		a.objective(), greedyCost, bestCost, improvement, done, time.Since(started).Round(time.Millisecond))
+
+	return flattenUnits(best)
+}
+
+// moveUnit переносит часть с позиции from на позицию to, сдвигая остальные переставляемые части
+func moveUnit(units []taskUnit, positions []int, from, to int) {
+	var fi, ti int
+	for n, p := range positions {
+		if p == from {
+			fi = n
+		}
+		if p == to {
+			ti = n
+		}
+	}
+	moved := units[positions[fi]]
+	if fi < ti {
+		for n := fi; n < ti; n++ {
+			units[positions[n]] = units[positions[n+1]]
+		}
+	} else {
+		for n := fi; n > ti; n-- {
+			units[positions[n]] = units[positions[n-1]]
+		}
+	}
+	units[positions[ti]] = moved
+}
+
+func flattenUnits(units []taskUnit) []*LevelingTask {
+	var tasks []*LevelingTask
+	for _, u := range units {
+		tasks = append(tasks, u...)
+	}
+	return tasks
+}
+
+func (a *annealer) objective() string {
+	if a.cfg.Objective == "" {
+		return ObjectiveWeightedTardiness
+	}
+	return a.cfg.Objective
+}
+
+// cost выравнивает задачи в копии слотов и возвращает значение целевой функции в часах
+func (a *annealer) cost(units []taskUnit) float64 {
+	tasks := flattenUnits(units)
+	scheduleTasks(tasks, a.slots.Clone(), a.timeline)
+
+	var total float64
+	for _, task := range tasks {
+		if task.Summary {
+			continue
+		}
+		switch a.objective() {
+		case ObjectiveMakespan:
+			total = math.Max(total, task.Finish.Hours())
+		default:
+			deadline, ok := a.deadlines[task]
+			if ok && task.Finish > deadline {
+				total += (task.Finish - deadline).Hours() * a.weights[task]
+			}
+		}
+	}
+	return total
+}
+
+// taskWeight возвращает вес задачи из поля или по приоритету, по умолчанию 1
+func taskWeight(task *LevelingTask, cfg AnnealConfig) float64 {
+	if cfg.WeightField != "" {
+		if w, ok := task.Issue.FieldNumber(cfg.WeightField); ok {
+			return w
+		}
+	}
+	if w, ok := cfg.PriorityWeights[strings.ToLower(task.Issue.Priority())]; ok {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestValidateAnnealConfigPriorityWeights(t *testing.T) {
+	deadlines := DeadlinesConfig{Enabled: true}
+
+	cfg := AnnealConfig{PriorityWeights: map[string]float64{"Highest": 5, "low": 0.5}}
+	if err := validateAnnealConfig(&cfg, deadlines); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PriorityWeights["highest"] != 5 || cfg.PriorityWeights["low"] != 0.5 {
+		t.Errorf("приоритеты не приведены к нижнему регистру: %v", cfg.PriorityWeights)
+	}
+
+	cfg = AnnealConfig{PriorityWeights: map[string]float64{"High": 3, "HIGH": 4}}
+	if err := validateAnnealConfig(&cfg, deadlines); err == nil {
+		t.Error("приоритеты, отличающиеся регистром, должны отклоняться")
+	}
+
+	cfg = AnnealConfig{}
+	if err := validateAnnealConfig(&cfg, DeadlinesConfig{}); err == nil {
+		t.Error("weighted_tardiness без сроков должен отклоняться")
+	}
+}
+
+func TestTaskWeight(t *testing.T) {
+	cfg := AnnealConfig{WeightField: "customfield_1", PriorityWeights: map[string]float64{"high": 3}}
+	tests := []struct {
+		name   string
+		fields string
+		want   float64
+	}{
+		{name: "по полю", fields: `{"customfield_1": 7, "priority": {"name": "High"}}`, want: 7},
+		{name: "по приоритету", fields: `{"priority": {"name": "HIGH"}}`, want: 3},
+		{name: "по умолчанию", fields: `{"priority": {"name": "Low"}}`, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &LevelingTask{Issue: testIssue(t, "T-1", tt.fields)}
+			if got := taskWeight(task, cfg); got != tt.want {
+				t.Errorf("taskWeight = %v, ожидается %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimizeOrderReducesTardiness(t *testing.T) {
+	timeline := testTimeline()
+	long := testTask(t, "T-1", 40*time.Hour, 20250131)
+	short := testTask(t, "T-2", 8*time.Hour, 20250107)
+	slots := NewSlots(timeline, 1, 0)
+
+	tasks := optimizeOrder([]*LevelingTask{long, short}, slots, timeline, AnnealConfig{Iterations: 200})
+	if tasks[0] != short {
+		t.Fatalf("задача со сроком раньше должна идти первой, порядок: %s, %s", tasks[0].Issue.Key, tasks[1].Issue.Key)
+	}
+	scheduleTasks(tasks, slots, timeline)
+	if late := findLateTasks(tasks, timeline); len(late) != 0 {
+		t.Errorf("опаздывающих задач %d, ожидается 0", len(late))
+	}
+}
+
+func TestOptimizeOrderKeepsFixedTasks(t *testing.T) {
+	timeline := testTimeline()
+	first := testTask(t, "T-1", 40*time.Hour, 20250131)
+	first.InProgress = true
+	short := testTask(t, "T-2", 8*time.Hour, 20250107)
+	other := testTask(t, "T-3", 8*time.Hour, 20250131)
+
+	tasks := optimizeOrder([]*LevelingTask{first, other, short}, NewSlots(timeline, 1, 0), timeline, AnnealConfig{Iterations: 200})
+	if tasks[0] != first {
+		t.Errorf("задача в работе не должна переставляться, первая задача: %s", tasks[0].Issue.Key)
+	}
+}
+
+func testIssue(t *testing.T, key, fields string) JiraIssue {
+	t.Helper()
+	issue := JiraIssue{Key: key}
+	if err := json.Unmarshal([]byte(fields), &issue.Fields); err != nil {
+		t.Fatal(err)
+	}
+	return issue
+}
+
+func testTask(t *testing.T, key string, d time.Duration, deadline int) *LevelingTask {
+	t.Helper()
+	due, err := parseDateId(deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &LevelingTask{
+		Issue:      testIssue(t, key, `{}`),
+		Attributes: &StructureRowAttributes{},
+		Duration:   d,
+		Deadline:   due,
+		Slot:       -1,
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultPool — пул задач, не подошедших ни под одно правило: parallel_projects слотов
+// и ресурсы, не указанные в пулах
+const DefaultPool = "default"
+
+// Pool — слоты для задач одного вида работ. Пулы выравниваются независимо
+// с общим календарем и начальной задержкой.
+type Pool struct {
+	Name   string
+	Match  PoolMatchConfig
+	Shared string // общий пул, nil Slots — слоты общего пула создаются при совместном выравнивании
+	Slots  *Slots
+	Tasks  []*LevelingTask
+}
+
+func validatePoolsConfig(pools []PoolConfig) error {
+	names := make(map[string]bool)
+	for _, pc := range pools {
+		if pc.Name == "" {
+			return errors.New("не указано имя пула")
+		}
+		if pc.Name == DefaultPool {
+			return fmt.Errorf("имя пула '%s' зарезервировано для задач без пула", DefaultPool)
+		}
+		if names[pc.Name] {
+			return fmt.Errorf("пул '%s' указан несколько раз", pc.Name)
+		}
+		names[pc.Name] = true
+		if (pc.Match.Field == "") != (len(pc.Match.Values) == 0) {
+			return fmt.Errorf("пул '%s': field и values указываются вместе", pc.Name)
+		}
+		if pc.Shared != "" {
+			if pc.Capacity != 0 || len(pc.CapacitySchedule) > 0 || len(pc.Resources) > 0 {
+				return fmt.Errorf("пул '%s': capacity, capacity_schedule и resources общего пула задаются в shared_pools", pc.Name)
+			}
+			continue
+		}
+		capacity, err := slotCapacity(pc.Capacity, pc.CapacitySchedule)
+		if err != nil {
+			return fmt.Errorf("пул '%s': %w", pc.Name, err)
+		}
+		if capacity.Max() == 0 && len(pc.Resources) == 0 {
+			return fmt.Errorf("пул '%s': не указаны capacity или resources", pc.Name)
+		}
+	}
+	return nil
+}
+
+// buildPools создает слоты пулов. Без настроенных пулов все задачи попадают в пул по умолчанию.
+func buildPools(cfg StructureConfig, resources []*Resource, timeline Timeline, delay time.Duration) ([]*Pool, error) {
+	byName := make(map[string]*Resource, len(resources))
+	for _, r := range resources {
+		byName[r.Name] = r
+	}
+
+	var pools []*Pool
+	used := make(map[string]string)
+	for _, pc := range cfg.Pools {
+		if pc.Shared != "" {
+			pools = append(pools, &Pool{Name: pc.Name, Match: pc.Match, Shared: pc.Shared})
+			continue
+		}
+		var poolResources []*Resource
+		for _, name := range pc.Resources {
+			r, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("пул '%s': неизвестный ресурс '%s'", pc.Name, name)
+			}
+			if other, ok := used[name]; ok {
+				return nil, fmt.Errorf("ресурс '%s' указан в пулах '%s' и '%s'", name, other, pc.Name)
+			}
+			used[name] = pc.Name
+			poolResources = append(poolResources, r)
+		}
+		capacity, err := slotCapacity(pc.Capacity, pc.CapacitySchedule)
+		if err != nil {
+			return nil, fmt.Errorf("пул '%s': %w", pc.Name, err)
+		}
+		pools = append(pools, newPool(pc.Name, pc.Match, timeline, capacity, poolResources, delay))
+	}
+
+	var rest []*Resource
+	for _, r := range resources {
+		if _, ok := used[r.Name]; !ok {
+			rest = append(rest, r)
+		}
+	}
+	capacity, err := slotCapacity(cfg.ParallelProjects, cfg.ParallelProjectsSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("parallel_projects_schedule: %w", err)
+	}
+	if len(cfg.Pools) == 0 || capacity.Max() > 0 || len(rest) > 0 {
+		pools = append(pools, newPool(DefaultPool, PoolMatchConfig{}, timeline, capacity, rest, delay))
+	}
+	return pools, nil
+}
+
+func newPool(name string, match PoolMatchConfig, timeline Timeline, capacity SlotCapacity, resources []*Resource, delay time.Duration) *Pool {
+	slots := NewScheduledSlots(timeline, capacity, delay)
+	if len(capacity.Periods) > 0 && len(resources) > capacity.Max() {
+		log.Printf("[WARNING] Пул %s: ресурсов %d больше наибольшего количества слотов по расписанию %d, лишние ресурсы не получат открытых слотов\n", name, len(resources), capacity.Max())
+	}
+	if len(resources) > 0 {
+		slots.AssignResources(resources, delay)
+	}
+	return &Pool{Name: name, Match: match, Slots: slots}
+}
+
+// slotCapacity проверяет количество слотов и его изменения по датам
+func slotCapacity(base int, schedule []CapacityPeriodConfig) (SlotCapacity, error) {
+	if base < 0 {
+		return SlotCapacity{}, errors.New("отрицательное количество слотов")
+	}
+	c := SlotCapacity{Base: base}
+	for _, p := range schedule {
+		if p.Slots < 0 {
+			return SlotCapacity{}, fmt.Errorf("отрицательное количество слотов с %d", p.FromDateId)
+		}
+		if p.FromDateId > 0 && p.ToDateId > 0 && p.ToDateId < p.FromDateId {
+			return SlotCapacity{}, fmt.Errorf("to_date_id %d раньше from_date_id %d", p.ToDateId, p.FromDateId)
+		}
+		c.Periods = append(c.Periods, CapacityPeriod{FromDateId: p.FromDateId, ToDateId: p.ToDateId, Slots: p.Slots})
+	}
+	return c, nil
+}
+
+// Matches сообщает, подходит ли задача под правило пула
+func (m PoolMatchConfig) Matches(issue JiraIssue) bool {
+	if len(m.Components) > 0 && !containsAnyFold(issue.Components(), m.Components) {
+		return false
+	}
+	if len(m.Labels) > 0 && !containsAnyFold(issue.Labels(), m.Labels) {
+		return false
+	}
+	if len(m.IssueTypes) > 0 && !containsAnyFold([]string{issue.IssueType()}, m.IssueTypes) {
+		return false
+	}
+	if m.Field != "" && !containsAnyFold(issue.FieldStrings(m.Field), m.Values) {
+		return false
+	}
+	return true
+}
+
+func containsAnyFold(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if strings.EqualFold(v, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assignPools распределяет задачи по пулам в порядке списка: задача попадает в пул, заданный правилом,
+// или в первый подходящий пул.
+// Строки-родители не занимают слоты и возвращаются отдельно, задачи без пула пропускаются.
+func assignPools(tasks []*LevelingTask, pools []*Pool) []*LevelingTask {
+	var summaries []*LevelingTask
+	for _, task := range tasks {
+		if task.Summary {
+			summaries = append(summaries, task)
+			continue
+		}
+		var pool *Pool
+		for _, p := range pools {
+			matched := p.Name == task.ForcedPool
+			if task.ForcedPool == "" {
+				matched = p.Match.Matches(task.Issue)
+			}
+			if matched {
+				pool = p
+				break
+			}
+		}
+		if pool == nil {
+			log.Printf("[WARNING] Задача %s не подходит ни под один пул и не будет выровнена\n", task.Issue.Key)
+			continue
+		}
+		if task.ForcedSlot != nil && pool.Slots != nil && *task.ForcedSlot >= pool.Slots.Len() {
+			log.Printf("[WARNING] Задача %s: в пуле %s нет слота %d, слот будет выбран при выравнивании\n", task.Issue.Key, pool.Name, *task.ForcedSlot)
+			task.ForcedSlot = nil
+		}
+		task.Pool = pool.Name
+		pool.Tasks = append(pool.Tasks, task)
+	}
+	return summaries
+}
+
+// levelPools выравнивает задачи каждого пула структуры в его слотах и возвращает задачи всех пулов.
+// Задачи общих пулов не выравниваются.
+func levelPools(strategy LevelingStrategy, pools []*Pool, timeline Timeline) ([]*LevelingTask, error) {
+	var result []*LevelingTask
+	for _, pool := range pools {
+		if pool.Shared != "" {
+			log.Printf("Пул %s: общий пул %s, задач %d\n", pool.Name, pool.Shared, len(pool.Tasks))
+			continue
+		}
+		if len(pools) > 1 {
+			log.Printf("Пул %s: слотов %d, задач %d\n", pool.Name, pool.Slots.Len(), len(pool.Tasks))
+		}
+		if len(pool.Tasks) == 0 {
+			continue
+		}
+		leveled, err := strategy.Level(LevelingInput{Tasks: pool.Tasks, Slots: pool.Slots, Timeline: timeline})
+		if err != nil {
+			return nil, fmt.Errorf("пул %s: %w", pool.Name, err)
+		}
+		result = append(result, leveled...)
+	}
+	return result, nil
+}
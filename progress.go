@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Действия с завершенными задачами
+const (
+	DoneExclude = "exclude" // задача не выравнивается и не занимает слот
+	DonePin     = "pin"     // задача занимает слот в своих текущих датах, задержка не меняется
+)
+
+func validateProgressConfig(cfg ProgressConfig) error {
+	if cfg.Done != "" && cfg.Done != DoneExclude && cfg.Done != DonePin {
+		return fmt.Errorf("неизвестное действие с завершенными задачами: '%s'", cfg.Done)
+	}
+	return nil
+}
+
+// applyProgress учитывает состояние задач: завершенные исключаются или закрепляются,
+// для задач в работе выравнивается только оставшаяся работа, и они ставятся в начало списка,
+// чтобы продолжиться с текущей даты.
+func applyProgress(tasks []*LevelingTask, cfg ProgressConfig) []*LevelingTask {
+	if !cfg.Enabled {
+		return tasks
+	}
+
+	var inProgress, rest []*LevelingTask
+	var excluded, pinned int
+	for _, task := range tasks {
+		issue := task.Issue
+		switch {
+		case issue.StatusCategory() == "done" || issue.Resolution() != "":
+			if cfg.Done == DonePin && task.Pin() {
+				pinned++
+				rest = append(rest, task)
+				log.Printf("Задача %s завершена и будет закреплена в текущих датах\n", issue.Key)
+				continue
+			}
+			excluded++
+			log.Printf("Задача %s завершена и будет исключена из выравнивания\n", issue.Key)
+		case issue.StatusCategory() == "indeterminate":
+			full := task.Duration
+			task.Duration = time.Duration(float64(task.Duration) * remainingFraction(task, cfg))
+			task.InProgress = true
+			inProgress = append(inProgress, task)
+			log.Printf("Задача %s в работе, оставшаяся длительность %s из %s\n", issue.Key, task.Duration, full)
+		default:
+			rest = append(rest, task)
+		}
+	}
+
+	log.Printf("Задач в работе: %d, завершенных исключено: %d, закреплено: %d\n", len(inProgress), excluded, pinned)
+	return append(inProgress, rest...)
+}
+
+// remainingFraction возвращает долю оставшейся работы: по прогрессу из Gantt,
+// затем по соотношению затраченного времени и оставшейся оценки
+func remainingFraction(task *LevelingTask, cfg ProgressConfig) float64 {
+	if cfg.GanttAttribute != "" {
+		if p, ok := parseProgress(task.Attributes.Extra[cfg.GanttAttribute]); ok {
+			return 1 - p
+		}
+	}
+
+	// Длительность уже взята из оставшейся оценки
+	if task.DurationSource == DurationSourceRemainingEstimate {
+		return 1
+	}
+
+	spent, hasSpent := task.Issue.TimeSpent()
+	if !hasSpent || spent <= 0 {
+		return 1
+	}
+	if remaining, ok := task.Issue.RemainingEstimate(); ok {
+		return float64(remaining) / float64(spent+remaining)
+	}
+	if task.Duration > 0 {
+		return max(0, 1-float64(spent)/float64(task.Duration))
+	}
+	return 1
+}
+
+// parseProgress разбирает прогресс в виде "45%", "45" или "0.45" и возвращает долю от 0 до 1.
+// Число без знака процента от 0 до 1 считается долей, больше 1 — процентом: "1" — это 100%, 1% записывается как "1%".
+func parseProgress(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	percent := strings.HasSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+	if err != nil {
+		return 0, false
+	}
+	if percent || v > 1 {
+		v /= 100
+	}
+	return min(max(v, 0), 1), true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resource описывает доступность исполнителя, закрепленного за слотом
+type Resource struct {
+	Name        string
+	WeekHours   []time.Duration // часы по дням недели начиная с понедельника, nil — как в календаре Ганта
+	StartDateId int             // первый рабочий день, 0 — без ограничения
+	EndDateId   int             // последний рабочий день, 0 — без ограничения
+	Days        map[int]time.Duration
+}
+
+// GetAvailableDurationForDate возвращает рабочее время ресурса в указанный день.
+// Ресурс не может работать больше, чем позволяет календарь Ганта.
+func (r *Resource) GetAvailableDurationForDate(cal *Calendar, dateId int) time.Duration {
+	base := cal.GetWorkingDurationForDate(dateId)
+	if r.StartDateId > 0 && dateId < r.StartDateId {
+		return 0
+	}
+	if r.EndDateId > 0 && dateId > r.EndDateId {
+		return 0
+	}
+
+	available := base
+	if r.WeekHours != nil {
+		t, err := parseDateId(dateId)
+		if err != nil {
+			return 0
+		}
+		available = r.WeekHours[mondayBasedWeekday(t)]
+	}
+	if d, ok := r.Days[dateId]; ok {
+		available = d
+	}
+	return min(available, base)
+}
+
+// Schedule размещает работу длительностью d на ресурсе не раньше смещения from.
+// Возвращает смещения начала и окончания работы во времени календаря Ганта:
+// в дни отпуска время идет, а работа не выполняется, при неполной занятости работа растягивается.
+func (r *Resource) Schedule(t Timeline, from, d time.Duration) (time.Duration, time.Duration, error) {
+	start, finish, err := t.ScheduleWork(from, d, func(dateId int) (time.Duration, bool) {
+		if r.EndDateId > 0 && dateId > r.EndDateId {
+			return 0, false
+		}
+		return r.GetAvailableDurationForDate(t.Calendar, dateId), true
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("ресурс '%s': %w", r.Name, err)
+	}
+	return start, finish, nil
+}
+
+func scaleDuration(d, num, den time.Duration) time.Duration {
+	return time.Duration(float64(d) * float64(num) / float64(den))
+}
+
+// loadResources собирает ресурсы из конфигурации структуры и CSV-файла
+func loadResources(cfg StructureConfig) ([]*Resource, error) {
+	var resources []*Resource
+	byName := make(map[string]*Resource)
+
+	for _, rc := range cfg.Resources {
+		if rc.Name == "" {
+			return nil, errors.New("не указано имя ресурса")
+		}
+		if _, ok := byName[rc.Name]; ok {
+			return nil, fmt.Errorf("ресурс '%s' указан несколько раз", rc.Name)
+		}
+		r := &Resource{
+			Name:        rc.Name,
+			StartDateId: rc.StartDateId,
+			EndDateId:   rc.EndDateId,
+			Days:        make(map[int]time.Duration),
+		}
+		if len(rc.WeekHours) > 0 {
+			if len(rc.WeekHours) != 7 {
+				return nil, fmt.Errorf("ресурс '%s': week_hours должен содержать 7 значений", rc.Name)
+			}
+			r.WeekHours = make([]time.Duration, 7)
+			for i, h := range rc.WeekHours {
+				r.WeekHours[i] = time.Duration(h * float64(time.Hour))
+			}
+		}
+		for _, a := range rc.Absences {
+			if err := r.setDays(a.DateId, a.ToDateId, a.Hours); err != nil {
+				return nil, fmt.Errorf("ресурс '%s': %w", rc.Name, err)
+			}
+		}
+		resources = append(resources, r)
+		byName[r.Name] = r
+	}
+
+	if cfg.ResourcesFile != "" {
+		var err error
+		resources, err = loadResourcesCSV(cfg.ResourcesFile, resources, byName)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла ресурсов %s: %w", cfg.ResourcesFile, err)
+		}
+	}
+
+	return resources, nil
+}
+
+// loadResourcesCSV читает строки вида "resource,date_id,to_date_id,hours,comment".
+// Ресурсы, не описанные в конфигурации, добавляются с календарем Ганта.
+func loadResourcesCSV(path string, resources []*Resource, byName map[string]*Resource) ([]*Resource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 || strings.HasPrefix(record[0], "#") || (line == 1 && record[0] == "resource") {
+			continue
+		}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("строка %d: ожидается resource,date_id,to_date_id,hours[,comment]", line)
+		}
+
+		dateId, err := parseInt(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректный date_id: %w", line, err)
+		}
+		var toDateId int
+		if record[2] != "" {
+			toDateId, err = parseInt(record[2])
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: некорректный to_date_id: %w", line, err)
+			}
+		}
+		hours, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректное количество часов: %w", line, err)
+		}
+
+		r, ok := byName[record[0]]
+		if !ok {
+			r = &Resource{Name: record[0], Days: make(map[int]time.Duration)}
+			resources = append(resources, r)
+			byName[r.Name] = r
+		}
+		if err := r.setDays(dateId, toDateId, hours); err != nil {
+			return nil, fmt.Errorf("строка %d: %w", line, err)
+		}
+	}
+	return resources, nil
+}
+
+func (r *Resource) setDays(dateId, toDateId int, hours float64) error {
+	if toDateId <= 0 {
+		toDateId = dateId
+	}
+	if toDateId < dateId {
+		return fmt.Errorf("to_date_id %d меньше date_id %d", toDateId, dateId)
+	}
+	next, err := nextDateId(toDateId)
+	if err != nil {
+		return err
+	}
+	for _, id := range dateIdsBetween(dateId, next) {
+		r.Days[id] = time.Duration(hours * float64(time.Hour))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Действия правил для задач
+const (
+	RuleActionPin     = "pin"     // задача занимает слот в своих текущих датах, задержка не меняется
+	RuleActionExclude = "exclude" // задача не выравнивается и не занимает слот
+	RuleActionPool    = "pool"    // задача выравнивается в указанном пуле и, если указан, слоте
+)
+
+// validateIssueRules проверяет действия правил, пулы и слоты, в которые они направляют задачи.
+// Проверка идет по построенным пулам: пул default создается не всегда, например при заданных пулах,
+// parallel_projects: 0 и отсутствии ресурсов вне пулов.
+func validateIssueRules(rules []IssueRuleConfig, pools []*Pool) error {
+	names := make(map[string]bool, len(pools))
+	for _, pool := range pools {
+		names[pool.Name] = true
+	}
+	for i, rule := range rules {
+		switch rule.Action {
+		case RuleActionPin, RuleActionExclude:
+			if rule.Pool != "" || rule.Slot != nil {
+				return fmt.Errorf("правило %d: pool и slot указываются только для действия %s", i+1, RuleActionPool)
+			}
+		case RuleActionPool:
+			if rule.Pool == "" && rule.Slot == nil {
+				return fmt.Errorf("правило %d: не указаны pool или slot", i+1)
+			}
+			if rule.Pool != "" && !names[rule.Pool] {
+				return fmt.Errorf("правило %d: неизвестный пул '%s'", i+1, rule.Pool)
+			}
+			if rule.Slot != nil && *rule.Slot < 0 {
+				return fmt.Errorf("правило %d: отрицательный номер слота", i+1)
+			}
+		default:
+			return fmt.Errorf("правило %d: неизвестное действие '%s'", i+1, rule.Action)
+		}
+		if (rule.Match.Field == "") != (len(rule.Match.Values) == 0) {
+			return fmt.Errorf("правило %d: field и values указываются вместе", i+1)
+		}
+	}
+	return nil
+}
+
+// applyIssueRules применяет к задачам первое подходящее правило: закрепляет, исключает
+// или направляет в пул и слот. Возвращает задачи без исключенных.
+func applyIssueRules(client *JiraClient, tasks []*LevelingTask, rules []IssueRuleConfig) ([]*LevelingTask, error) {
+	if len(rules) == 0 {
+		return tasks, nil
+	}
+
+	// JQL-фильтры правил выполняются один раз для всех задач
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.Issue.ID)
+	}
+	jqlMatches := make([]map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.Match.JQL == "" {
+			continue
+		}
+		matched, err := filterIssueIDs(client, ids, rule.Match.JQL)
+		if err != nil {
+			return nil, fmt.Errorf("правило %d: ошибка выполнения JQL: %w", i+1, err)
+		}
+		jqlMatches[i] = matched
+	}
+
+	result := make([]*LevelingTask, 0, len(tasks))
+	var pinned, excluded, forced int
+	for _, task := range tasks {
+		n := -1
+		for i, rule := range rules {
+			if rule.Match.Matches(task.Issue) && (jqlMatches[i] == nil || jqlMatches[i][task.Issue.ID]) {
+				n = i
+				break
+			}
+		}
+		if n < 0 || task.Summary {
+			result = append(result, task)
+			continue
+		}
+
+		rule := rules[n]
+		switch rule.Action {
+		case RuleActionExclude:
+			excluded++
+			log.Printf("Задача %s исключена из выравнивания правилом %d\n", task.Issue.Key, n+1)
+			continue
+		case RuleActionPin:
+			if task.Pin() {
+				pinned++
+				log.Printf("Задача %s закреплена в текущих датах правилом %d\n", task.Issue.Key, n+1)
+			}
+		case RuleActionPool:
+			task.ForcedPool, task.ForcedSlot = rule.Pool, rule.Slot
+			forced++
+			log.Printf("Задача %s направлена правилом %d в %s\n", task.Issue.Key, n+1, describeForcedSlot(rule.Pool, rule.Slot))
+		}
+		result = append(result, task)
+	}
+	log.Printf("Правила для задач: закреплено %d, исключено %d, направлено в пул или слот %d\n", pinned, excluded, forced)
+	warnSplitGroups(result)
+	return result, nil
+}
+
+// warnSplitGroups выводит задачи, которые правило направляет в слот, отличный от слота их группы.
+// Слот из правила важнее keep_children_together: группа занимает слот своей первой задачи,
+// задача с другим слотом из правила ставится в него, а следующие задачи группы — за ней.
+func warnSplitGroups(tasks []*LevelingTask) {
+	type groupSlot struct {
+		slot   *int // слот первой задачи группы из правила, nil — выбирается при выравнивании
+		pool   string
+		broken bool
+	}
+	groups := make(map[int]*groupSlot)
+	for _, task := range tasks {
+		if task.Group == 0 || task.Summary {
+			continue
+		}
+		g, ok := groups[task.Group]
+		if !ok {
+			groups[task.Group] = &groupSlot{slot: task.ForcedSlot, pool: task.ForcedPool}
+			continue
+		}
+		if task.ForcedSlot == nil || g.broken {
+			continue
+		}
+		if g.slot == nil || *g.slot != *task.ForcedSlot || g.pool != task.ForcedPool {
+			g.broken = true
+			log.Printf("[WARNING] Задача %s направлена правилом в %s, задачи строки %d могут оказаться в разных слотах\n",
+				task.Issue.Key, describeForcedSlot(task.ForcedPool, task.ForcedSlot), task.Group)
+		}
+	}
+}
+
+// describeForcedSlot возвращает описание пула и слота, заданных правилом, для вывода в лог
+func describeForcedSlot(pool string, slot *int) string {
+	var parts []string
+	if pool != "" {
+		parts = append(parts, "пул "+pool)
+	}
+	if slot != nil {
+		parts = append(parts, fmt.Sprintf("слот %d", *slot))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Matches сообщает, подходит ли задача под условия правила, кроме JQL-фильтра
+func (m IssueMatchConfig) Matches(issue JiraIssue) bool {
+	if len(m.Statuses) > 0 && !containsAnyFold([]string{issue.Status()}, m.Statuses) {
+		return false
+	}
+	return m.PoolMatchConfig.Matches(issue)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestValidateIssueRulesRequiresBuiltPool(t *testing.T) {
+	rules := []IssueRuleConfig{{Action: RuleActionPool, Pool: DefaultPool}}
+
+	if err := validateIssueRules(rules, []*Pool{{Name: "backend"}}); err == nil {
+		t.Error("правило с пулом default принято, хотя пул default не создан")
+	}
+	if err := validateIssueRules(rules, []*Pool{{Name: "backend"}, {Name: DefaultPool}}); err != nil {
+		t.Errorf("правило с созданным пулом default отклонено: %v", err)
+	}
+}
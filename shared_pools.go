@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+func usesSharedPools(structure StructureConfig) bool {
+	for _, pc := range structure.Pools {
+		if pc.Shared != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedStructures проверяет ссылки на общие пулы и возвращает имена структур, которые их используют
+func sharedStructures(cfg *FileConfig) ([]string, error) {
+	var names []string
+	for name, structure := range cfg.Structures {
+		if !usesSharedPools(structure) {
+			continue
+		}
+		if structure.SharedWeight < 0 {
+			return nil, fmt.Errorf("структура '%s': отрицательный shared_weight", name)
+		}
+		for _, pc := range structure.Pools {
+			if _, ok := cfg.SharedPools[pc.Shared]; pc.Shared != "" && !ok {
+				return nil, fmt.Errorf("структура '%s': пул '%s' ссылается на неизвестный общий пул '%s'", name, pc.Name, pc.Shared)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// calculateSharedLeveling выравнивает структуры с общими пулами за один расчет: собственные пулы каждой
+// структуры выравниваются отдельно, задачи общих пулов всех структур — в общих слотах.
+// Задержки записываются только в структуры из write, остальные структуры занимают общие слоты.
+func calculateSharedLeveling(client *JiraClient, cfg *FileConfig, names []string, write map[string]bool) error {
+	runs := make([]*levelingRun, 0, len(names))
+	for _, name := range names {
+		log.Printf("Подготавливаем структуру '%s' к совместному выравниванию\n", name)
+		run, err := prepareLeveling(client, cfg.Structures[name])
+		if err != nil {
+			return fmt.Errorf("структура '%s': %w", name, err)
+		}
+		if err := run.level(); err != nil {
+			return fmt.Errorf("структура '%s': ошибка выравнивания: %w", name, err)
+		}
+		runs = append(runs, run)
+	}
+
+	shared := make([]string, 0, len(cfg.SharedPools))
+	for name := range cfg.SharedPools {
+		shared = append(shared, name)
+	}
+	sort.Strings(shared)
+	for _, name := range shared {
+		if err := levelSharedPool(name, cfg.SharedPools[name], runs); err != nil {
+			return fmt.Errorf("общий пул '%s': %w", name, err)
+		}
+	}
+
+	for i, run := range runs {
+		if !write[names[i]] {
+			log.Printf("Структура '%s' учтена в общих пулах, задержки не записываются\n", names[i])
+			continue
+		}
+		log.Printf("Выставляем задержки выравнивания для структуры '%s'\n", names[i])
+		if err := run.apply(client); err != nil {
+			return fmt.Errorf("структура '%s': %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// levelSharedPool объединяет задачи общего пула из всех структур и выравнивает их жадной стратегией.
+// Общие слоты работают по календарю первой структуры, задержки переводятся в диаграмму каждой структуры.
+func levelSharedPool(name string, cfg SharedPoolConfig, runs []*levelingRun) error {
+	var lists [][]*LevelingTask
+	var weights []float64
+	var timeline *Timeline
+	var startDateId int
+	owners := make(map[*LevelingTask]*levelingRun)
+	for _, run := range runs {
+		for _, pool := range run.Pools {
+			if pool.Shared != name {
+				continue
+			}
+			if timeline == nil {
+				timeline = &run.Timeline
+			}
+			startDateId = max(startDateId, run.StartDateId)
+			for _, task := range pool.Tasks {
+				owners[task] = run
+			}
+			lists = append(lists, pool.Tasks)
+			weights = append(weights, sharedWeight(run.Structure))
+		}
+	}
+	if timeline == nil {
+		return nil
+	}
+
+	resources, err := loadResources(StructureConfig{Resources: cfg.Resources, ResourcesFile: cfg.ResourcesFile})
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки ресурсов: %w", err)
+	}
+	delay := max(0, timeline.OffsetForDate(startDateId))
+	capacity, err := slotCapacity(cfg.Capacity, cfg.CapacitySchedule)
+	if err != nil {
+		return err
+	}
+	pool := newPool(name, PoolMatchConfig{}, *timeline, capacity, resources, delay)
+	if pool.Slots.Len() == 0 {
+		return fmt.Errorf("не указаны capacity или resources")
+	}
+
+	tasks := mergeWeighted(lists, weights)
+	log.Printf("Общий пул %s: слотов %d, задач %d из %d структур\n", name, pool.Slots.Len(), len(tasks), len(lists))
+	tasks, err = greedyStrategy{}.Level(LevelingInput{Tasks: tasks, Slots: pool.Slots, Timeline: *timeline})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		run := owners[task]
+		delay, err := timeline.ConvertOffset(task.LevelingDelay, run.Timeline)
+		if err != nil {
+			return fmt.Errorf("задача %s: %w", task.Issue.Key, err)
+		}
+		finish, err := timeline.ConvertOffset(task.Finish, run.Timeline)
+		if err != nil {
+			return fmt.Errorf("задача %s: %w", task.Issue.Key, err)
+		}
+		task.LevelingDelay, task.Finish = max(0, delay), finish
+		run.Tasks = append(run.Tasks, task)
+	}
+	return nil
+}
+
+func sharedWeight(structure StructureConfig) float64 {
+	if structure.SharedWeight <= 0 {
+		return 1
+	}
+	return structure.SharedWeight
+}
+
+// mergeWeighted объединяет списки задач структур в один порядок. Следующая задача берется из списка,
+// у которого отношение уже взятой работы к весу наименьшее, поэтому слоты делятся пропорционально весам.
+// Порядок задач внутри списка сохраняется, группы дочерних задач не разрываются.
+func mergeWeighted(lists [][]*LevelingTask, weights []float64) []*LevelingTask {
+	next := make([]int, len(lists))
+	taken := make([]time.Duration, len(lists))
+	var result []*LevelingTask
+	for {
+		best := -1
+		for i, list := range lists {
+			if next[i] >= len(list) {
+				continue
+			}
+			if best < 0 || float64(taken[i])/weights[i] < float64(taken[best])/weights[best] {
+				best = i
+			}
+		}
+		if best < 0 {
+			return result
+		}
+
+		list := lists[best]
+		first := list[next[best]]
+		for next[best] < len(list) {
+			task := list[next[best]]
+			if task != first && (first.Group == 0 || task.Group != first.Group) {
+				break
+			}
+			result = append(result, task)
+			taken[best] += task.Duration
+			next[best]++
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeWeighted(t *testing.T) {
+	task := func(key string, group int) *LevelingTask {
+		return &LevelingTask{Issue: JiraIssue{Key: key}, Duration: 8 * time.Hour, Group: group}
+	}
+	a := []*LevelingTask{task("A-1", 0), task("A-2", 0), task("A-3", 0)}
+	b := []*LevelingTask{task("B-1", 5), task("B-2", 5), task("B-3", 0)}
+
+	merged := mergeWeighted([][]*LevelingTask{a, b}, []float64{2, 1})
+
+	// Структура A с весом 2 получает вдвое больше работы, группа B-1, B-2 не разрывается
+	want := []string{"A-1", "B-1", "B-2", "A-2", "A-3", "B-3"}
+	if len(merged) != len(want) {
+		t.Fatalf("задач %d, ожидается %d", len(merged), len(want))
+	}
+	for i, task := range merged {
+		if task.Issue.Key != want[i] {
+			t.Errorf("позиция %d — %s, ожидается %s", i, task.Issue.Key, want[i])
+		}
+	}
+}
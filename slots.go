@@ -2,42 +2,479 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 )
 
-type Slots []time.Duration
+// Slot хранит задержку от начала проекта, с которой слот может взять следующую задачу
+type Slot struct {
+	Delay    time.Duration
+	Resource *Resource     // nil — слот работает по календарю Ганта
+	Capacity *SlotCapacity // nil — слот открыт всегда
+	Context  string        // проект последней задачи слота
+	partial  []partialLoad
+}
+
+// partialLoad — часть слота, занятая задачей с неполной загрузкой в интервале дат
+type partialLoad struct {
+	FromDateId int
+	ToDateId   int // включительно
+	Fraction   float64
+}
+
+// load возвращает долю слота, занятую задачами с неполной загрузкой в указанный день
+func (s Slot) load(dateId int) float64 {
+	var total float64
+	for _, p := range s.partial {
+		if dateId >= p.FromDateId && dateId <= p.ToDateId {
+			total += p.Fraction
+		}
+	}
+	return total
+}
+
+// CapacityPeriod задает количество открытых слотов в интервале дат
+type CapacityPeriod struct {
+	FromDateId int // 0 — без ограничения
+	ToDateId   int // включительно, 0 — без ограничения
+	Slots      int
+}
+
+// SlotCapacity — количество открытых слотов по датам: в интервалах Periods (первый подходящий),
+// в остальные дни — Base. Слот с номером i открыт в дни, когда открыто больше i слотов.
+type SlotCapacity struct {
+	Base    int
+	Periods []CapacityPeriod
+}
+
+// At возвращает количество открытых слотов в указанный день
+func (c *SlotCapacity) At(dateId int) int {
+	for _, p := range c.Periods {
+		if (p.FromDateId == 0 || dateId >= p.FromDateId) && (p.ToDateId == 0 || dateId <= p.ToDateId) {
+			return p.Slots
+		}
+	}
+	return c.Base
+}
 
-func NewSlots(slots int, delay time.Duration) Slots {
-	s := make(Slots, slots)
-	for i := range s {
-		s[i] = delay
+// ClosedSince сообщает, что слот с номером slot закрыт в указанный день и больше не откроется
+func (c *SlotCapacity) ClosedSince(slot, dateId int) bool {
+	if c.At(dateId) > slot {
+		return false
+	}
+	// Количество слотов меняется только на границах интервалов. Проверяем значение на каждой границе,
+	// так как пересекающиеся интервалы могут перекрывать друг друга.
+	for _, p := range c.Periods {
+		bounds := []int{p.FromDateId}
+		if p.ToDateId > 0 {
+			if next, err := nextDateId(p.ToDateId); err == nil {
+				bounds = append(bounds, next)
+			}
+		}
+		for _, d := range bounds {
+			if d > dateId && c.At(d) > slot {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Max возвращает наибольшее количество одновременно открытых слотов
+func (c *SlotCapacity) Max() int {
+	n := c.Base
+	for _, p := range c.Periods {
+		n = max(n, p.Slots)
+	}
+	return n
+}
+
+type Slots struct {
+	Timeline   Timeline
+	SwitchCost time.Duration // время на переключение слота на задачу другого проекта
+	MaxWait    time.Duration // насколько позже может начаться задача, чтобы остаться в слоте своего проекта
+	WIP        *WIPTracker   // nil — без ограничений одновременно выполняемых задач
+	RoundStart bool          // работа начинается только с начала рабочего дня
+	Start      time.Duration // начало выравнивания, с которого продолжаются задачи в работе
+	items      []Slot
+	capacity   *SlotCapacity // расписание слотов, nil — слоты открыты всегда
+}
+
+func NewSlots(timeline Timeline, slots int, delay time.Duration) *Slots {
+	s := &Slots{
+		Timeline: timeline,
+		Start:    delay,
+		items:    make([]Slot, slots),
+	}
+	for i := range s.items {
+		s.items[i].Delay = delay
 	}
 	return s
 }
 
-func (s Slots) GetLevelingDelayAndAdd(d time.Duration) (time.Duration, error) {
-	slot, err := s.FindSlot()
+// NewScheduledSlots создает слоты, которые открываются и закрываются по датам согласно capacity
+func NewScheduledSlots(timeline Timeline, capacity SlotCapacity, delay time.Duration) *Slots {
+	if len(capacity.Periods) == 0 {
+		return NewSlots(timeline, capacity.Base, delay)
+	}
+	s := NewSlots(timeline, capacity.Max(), delay)
+	s.capacity = &capacity
+	for i := range s.items {
+		s.items[i].Capacity = s.capacity
+	}
+	return s
+}
+
+// AssignResources закрепляет ресурсы за слотами по порядку.
+// Если ресурсов больше, чем слотов, добавляются новые слоты с той же начальной задержкой и тем же расписанием.
+func (s *Slots) AssignResources(resources []*Resource, delay time.Duration) {
+	for len(s.items) < len(resources) {
+		s.items = append(s.items, Slot{Delay: delay, Capacity: s.capacity})
+	}
+	for i, r := range resources {
+		s.items[i].Resource = r
+	}
+}
+
+// Clone возвращает копию слотов, изменения которой не затрагивают исходные слоты
+func (s *Slots) Clone() *Slots {
+	c := &Slots{
+		Timeline:   s.Timeline,
+		SwitchCost: s.SwitchCost,
+		MaxWait:    s.MaxWait,
+		WIP:        s.WIP.Clone(),
+		RoundStart: s.RoundStart,
+		Start:      s.Start,
+		items:      make([]Slot, len(s.items)),
+		capacity:   s.capacity,
+	}
+	copy(c.items, s.items)
+	for i := range c.items {
+		c.items[i].partial = append([]partialLoad(nil), s.items[i].partial...)
+	}
+	return c
+}
+
+// restore возвращает слоты в состояние from. Ограничения одновременно выполняемых задач восстанавливаются
+// в том же WIPTracker, так как он общий для всех пулов структуры.
+func (s *Slots) restore(from *Slots) {
+	wip := s.WIP
+	*s = *from.Clone()
+	if wip != nil {
+		wip.restore(from.WIP)
+		s.WIP = wip
+	}
+}
+
+func (s *Slots) Len() int {
+	return len(s.items)
+}
+
+// Demand описывает работу, которую нужно поставить в слоты
+type Demand struct {
+	Duration time.Duration
+	Width    int        // количество слотов одновременно, 0 — один слот
+	Fraction float64    // доля загрузки слота, 0 — полная загрузка
+	Context  string     // проект задачи для учета переключения слотов
+	Limits   []WIPLimit // ограничения одновременно выполняемых задач
+
+	NotBefore time.Duration // работа не начинается раньше этого смещения
+	Buffer    time.Duration // время после работы, в течение которого слот остается занят
+}
+
+// Allocate ставит работу одновременно в dm.Width слотов, где она начнется раньше всего, с долей загрузки
+// каждого слота dm.Fraction. Слоты того же проекта предпочитаются, если работа начнется в них не позже
+// чем через MaxWait. Возвращает начало и окончание работы и занятые слоты.
+func (s *Slots) Allocate(dm Demand) (time.Duration, time.Duration, []int, error) {
+	return s.allocate(dm, -1)
+}
+
+// allocate выбирает слоты как Allocate; слот required, если он задан, входит в выбранные первым
+func (s *Slots) allocate(dm Demand, required int) (time.Duration, time.Duration, []int, error) {
+	width := max(dm.Width, 1)
+	if len(s.items) == 0 {
+		return 0, 0, nil, errors.New("no slots")
+	}
+	if width > len(s.items) {
+		return 0, 0, nil, fmt.Errorf("задаче нужно слотов: %d, доступно: %d", width, len(s.items))
+	}
+
+	type candidate struct {
+		slot  int
+		start time.Duration
+		rank  time.Duration
+		same  bool // слот последним выполнял задачу того же проекта
+	}
+	var candidates []candidate
+	var lastErr error
+	for i := range s.items {
+		st, _, err := s.earliest(i, dm)
+		if err != nil {
+			if i == required {
+				return 0, 0, nil, err
+			}
+			lastErr = err
+			continue
+		}
+		rank := st
+		same := dm.Context != "" && s.items[i].Context == dm.Context
+		if same {
+			rank -= s.MaxWait
+		}
+		candidates = append(candidates, candidate{slot: i, start: st, rank: rank, same: same})
+	}
+	if len(candidates) < width {
+		if lastErr == nil {
+			lastErr = errors.New("no slots")
+		}
+		return 0, 0, nil, lastErr
+	}
+	// При равном ранге предпочитается слот того же проекта, даже если max_wait не задан
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		return candidates[i].same && !candidates[j].same
+	})
+	chosen := candidates[:width]
+	if required >= 0 {
+		chosen = make([]candidate, 0, width)
+		for _, c := range candidates {
+			if c.slot == required {
+				chosen = append(chosen, c)
+			}
+		}
+		for _, c := range candidates {
+			if c.slot != required && len(chosen) < width {
+				chosen = append(chosen, c)
+			}
+		}
+	}
+
+	// Работа во всех слотах задачи идет одновременно: начало общее, окончание — самое позднее.
+	// Переключение с другого проекта уже учтено в начале каждого слота из earliest и завершается до общего начала.
+	var start, finish time.Duration
+	for _, c := range chosen {
+		start = max(start, c.start)
+	}
+	slots := make([]int, width)
+	for attempt := 0; ; attempt++ {
+		shifted := false
+		finish = 0
+		for n, c := range chosen {
+			slots[n] = c.slot
+			st, fin, err := s.place(c.slot, start, dm.Duration, dm.Fraction)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			if st > start {
+				start, shifted = s.roundStart(st), true
+				break
+			}
+			finish = max(finish, fin)
+		}
+		if !shifted {
+			if next, blocked := s.WIP.blocked(dm.Limits, start, finish); blocked {
+				start, shifted = s.roundStart(next), true
+			}
+		}
+		if !shifted {
+			break
+		}
+		if attempt >= maxTimelineDays {
+			return 0, 0, nil, errTimelineExhausted
+		}
+	}
+
+	for _, slot := range slots {
+		s.commit(slot, start, s.bufferEnd(slot, finish, dm), dm.Fraction, dm.Context)
+	}
+	s.WIP.Add(dm.Limits, start, finish)
+	return start, finish, slots, nil
+}
+
+// AddToSlot ставит работу в указанный слот сразу после предыдущей и возвращает ее начало и окончание.
+// Для работы на несколько слотов недостающие слоты выбираются так же, как в Allocate.
+func (s *Slots) AddToSlot(slot int, dm Demand) (time.Duration, time.Duration, error) {
+	if dm.Width > 1 {
+		start, finish, _, err := s.allocate(dm, slot)
+		return start, finish, err
+	}
+	start, finish, err := s.earliest(slot, dm)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.commit(slot, start, s.bufferEnd(slot, finish, dm), dm.Fraction, dm.Context)
+	s.WIP.Add(dm.Limits, start, finish)
+	return start, finish, nil
+}
+
+// Continue ставит уже начатую работу в слот с начала выравнивания независимо от занятости слота
+// и возвращает ее начало и окончание. Слот остается занят до окончания работы.
+func (s *Slots) Continue(slot int, dm Demand) (time.Duration, time.Duration, error) {
+	start, finish, err := s.place(slot, s.Start, dm.Duration, dm.Fraction)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.commit(slot, start, s.bufferEnd(slot, finish, dm), dm.Fraction, dm.Context)
+	s.WIP.Add(dm.Limits, start, finish)
+	return start, finish, nil
+}
+
+// earliest рассчитывает самое раннее начало и окончание работы в слоте с учетом ограничений одновременно
+// выполняемых задач и округления начала без изменения слота
+func (s *Slots) earliest(slot int, dm Demand) (time.Duration, time.Duration, error) {
+	from := max(s.items[slot].Delay, dm.NotBefore)
+	// После переноса начала слот простаивает, и переключение на другой проект успевает завершиться
+	switched := true
+	for i := 0; i < maxTimelineDays; i++ {
+		var start, finish time.Duration
+		var err error
+		if switched {
+			start, finish, err = s.placeTask(slot, from, dm)
+		} else {
+			start, finish, err = s.place(slot, from, dm.Duration, dm.Fraction)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if rounded := s.roundStart(start); rounded != start {
+			from, switched = rounded, false
+			continue
+		}
+		next, blocked := s.WIP.blocked(dm.Limits, start, finish)
+		if !blocked {
+			return start, finish, nil
+		}
+		from, switched = next, false
+	}
+	return 0, 0, errTimelineExhausted
+}
+
+// roundStart переносит начало работы в середине рабочего дня на начало следующего рабочего дня
+func (s *Slots) roundStart(start time.Duration) time.Duration {
+	if !s.RoundStart {
+		return start
+	}
+	dateId, into, err := s.Timeline.DateForOffset(start)
+	if err != nil || into == 0 {
+		return start
+	}
+	return s.Timeline.OffsetForDate(dateId) + s.Timeline.Calendar.GetWorkingDurationForDate(dateId)
+}
+
+// bufferEnd возвращает смещение, до которого слот остается занят после окончания работы с учетом буфера
+func (s *Slots) bufferEnd(slot int, finish time.Duration, dm Demand) time.Duration {
+	if dm.Buffer <= 0 {
+		return finish
+	}
+	_, end, err := s.place(slot, finish, dm.Buffer, dm.Fraction)
+	if err != nil {
+		return finish
+	}
+	return end
+}
+
+// placeTask рассчитывает начало и окончание работы в слоте не раньше смещения from.
+// Если слот переключается с другого проекта, работа начинается после времени на переключение.
+func (s *Slots) placeTask(slot int, from time.Duration, dm Demand) (time.Duration, time.Duration, error) {
+	if s.SwitchCost > 0 && dm.Context != "" && s.items[slot].Context != "" && s.items[slot].Context != dm.Context {
+		var err error
+		_, from, err = s.place(slot, from, s.SwitchCost, dm.Fraction)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return s.place(slot, from, dm.Duration, dm.Fraction)
+}
+
+// commit занимает слот задачей: при полной загрузке слот свободен только после окончания задачи,
+// при неполной — остаток слота в дни задачи доступен другим задачам
+func (s *Slots) commit(slot int, start, finish time.Duration, fraction float64, context string) {
+	if context != "" {
+		s.items[slot].Context = context
+	}
+	if fraction <= 0 || fraction >= 1 {
+		s.Reserve(slot, finish)
+		return
+	}
+	from, _, err := s.Timeline.DateForOffset(start)
+	if err != nil {
+		return
+	}
+	to, err := s.Timeline.FinishDateForOffset(finish)
 	if err != nil {
-		return 0, err
+		return
 	}
-	delay := s[slot]
-	s[slot] += d
-	return delay, nil
+	s.items[slot].partial = append(s.items[slot].partial, partialLoad{FromDateId: from, ToDateId: to, Fraction: fraction})
 }
 
-func (s Slots) FindSlot() (int, error) {
-	if len(s) == 0 {
+// place рассчитывает начало и окончание задачи длительностью d в слоте не раньше смещения from
+// без изменения слота. Задача с долей загрузки fraction выполняется медленнее и не больше свободной доли слота.
+func (s *Slots) place(slot int, from, d time.Duration, fraction float64) (time.Duration, time.Duration, error) {
+	item := s.items[slot]
+	if fraction <= 0 || fraction > 1 {
+		fraction = 1
+	}
+	if item.Capacity == nil && len(item.partial) == 0 && fraction == 1 {
+		if item.Resource == nil {
+			return from, from + d, nil
+		}
+		return item.Resource.Schedule(s.Timeline, from, d)
+	}
+
+	return s.Timeline.ScheduleWork(from, d, func(dateId int) (time.Duration, bool) {
+		var available time.Duration
+		more := true
+		switch {
+		case item.Capacity != nil && item.Capacity.At(dateId) <= slot:
+			// Пока слот закрыт, работа в нем не выполняется
+			return 0, !item.Capacity.ClosedSince(slot, dateId)
+		case item.Resource != nil:
+			r := item.Resource
+			available, more = r.GetAvailableDurationForDate(s.Timeline.Calendar, dateId), r.EndDateId == 0 || dateId <= r.EndDateId
+		default:
+			available = s.Timeline.Calendar.GetWorkingDurationForDate(dateId)
+		}
+		share := min(fraction, 1-item.load(dateId))
+		if share <= 0 {
+			return 0, more
+		}
+		return time.Duration(float64(available) * share), more
+	})
+}
+
+// FindSlot возвращает наименее загруженный слот из открытых в указанный день.
+// Если в этот день открытых слотов нет, выбирается наименее загруженный из всех.
+func (s *Slots) FindSlot(dateId int) (int, error) {
+	if len(s.items) == 0 {
 		return 0, errors.New("no slots")
 	}
-	i, d := 0, s[0]
-	for n, v := range s {
-		if d > v {
-			i, d = n, v
+	best, open := 0, false
+	for n, v := range s.items {
+		isOpen := v.Capacity == nil || v.Capacity.At(dateId) > n
+		if isOpen && !open || isOpen == open && v.Delay < s.items[best].Delay {
+			best, open = n, isOpen
 		}
 	}
-	return i, nil
+	return best, nil
+}
+
+func (s *Slots) SetDelay(slot int, d time.Duration) {
+	s.items[slot].Delay = d
 }
 
-func (s Slots) SetDelay(slot int, d time.Duration) {
-	s[slot] = d
+// SetContext запоминает проект задачи, которая заняла слот вне выравнивания
+func (s *Slots) SetContext(slot int, context string) {
+	if context != "" {
+		s.items[slot].Context = context
+	}
+}
+
+// Reserve занимает слот до смещения until, если слот освобождается раньше
+func (s *Slots) Reserve(slot int, until time.Duration) {
+	if s.items[slot].Delay < until {
+		s.items[slot].Delay = until
+	}
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testTimeline — календарь с рабочими днями по 8 часов с понедельника по пятницу, диаграмма начинается в понедельник 6 января 2025
+func testTimeline() Timeline {
+	day := DaySchedule{Duration: 8 * time.Hour}
+	cal := &Calendar{WeekDays: []DaySchedule{day, day, day, day, day, {}, {}}}
+	return Timeline{Calendar: cal, StartDateId: 20250106}
+}
+
+func TestSlotCapacityAt(t *testing.T) {
+	c := SlotCapacity{Base: 3, Periods: []CapacityPeriod{
+		{FromDateId: 20250201, ToDateId: 20250228, Slots: 1},
+		{FromDateId: 20250101, ToDateId: 20250331, Slots: 2},
+	}}
+	tests := []struct {
+		dateId int
+		want   int
+	}{
+		{20241231, 3},
+		{20250115, 2},
+		{20250210, 1}, // пересекающиеся интервалы: действует первый
+		{20250315, 2},
+		{20250401, 3},
+	}
+	for _, tt := range tests {
+		if got := c.At(tt.dateId); got != tt.want {
+			t.Errorf("At(%d) = %d, ожидается %d", tt.dateId, got, tt.want)
+		}
+	}
+	if c.Max() != 3 {
+		t.Errorf("Max() = %d, ожидается 3", c.Max())
+	}
+}
+
+func TestSlotCapacityClosedSince(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity SlotCapacity
+		slot     int
+		dateId   int
+		want     bool
+	}{
+		{
+			name:     "открыт",
+			capacity: SlotCapacity{Base: 2},
+			slot:     1, dateId: 20250110,
+		},
+		{
+			name:     "закрыт до конца",
+			capacity: SlotCapacity{Base: 2, Periods: []CapacityPeriod{{FromDateId: 20250110, Slots: 1}}},
+			slot:     1, dateId: 20250115, want: true,
+		},
+		{
+			name: "откроется позже",
+			capacity: SlotCapacity{Base: 1, Periods: []CapacityPeriod{
+				{FromDateId: 20250201, Slots: 2},
+			}},
+			slot: 1, dateId: 20250110,
+		},
+		{
+			name:     "откроется после окончания интервала",
+			capacity: SlotCapacity{Base: 2, Periods: []CapacityPeriod{{FromDateId: 20250101, ToDateId: 20250131, Slots: 0}}},
+			slot:     1, dateId: 20250110,
+		},
+		{
+			name: "интервал перекрыт более ранним",
+			capacity: SlotCapacity{Base: 3, Periods: []CapacityPeriod{
+				{FromDateId: 20250101, Slots: 0},
+				{FromDateId: 20250201, ToDateId: 20250228, Slots: 3},
+			}},
+			slot: 0, dateId: 20250110, want: true,
+		},
+		{
+			name:     "закрыт после окончания интервала",
+			capacity: SlotCapacity{Base: 0, Periods: []CapacityPeriod{{ToDateId: 20250131, Slots: 2}}},
+			slot:     1, dateId: 20250201, want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capacity.ClosedSince(tt.slot, tt.dateId); got != tt.want {
+				t.Errorf("ClosedSince(%d, %d) = %v, ожидается %v", tt.slot, tt.dateId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduledSlotsOpenAndClose(t *testing.T) {
+	timeline := testTimeline()
+	// С 13 января открывается второй слот, с 20 января остается один
+	capacity := SlotCapacity{Base: 1, Periods: []CapacityPeriod{{FromDateId: 20250113, ToDateId: 20250119, Slots: 2}}}
+	slots := NewScheduledSlots(timeline, capacity, 0)
+
+	start, finish, err := slots.place(1, 0, 16*time.Hour, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 40*time.Hour || finish != 56*time.Hour {
+		t.Errorf("работа во втором слоте: %s–%s, ожидается 40h–56h", start, finish)
+	}
+
+	if _, _, err := slots.place(1, 0, 48*time.Hour, 1); err == nil {
+		t.Error("работа, которая не помещается до закрытия слота, не должна размещаться")
+	}
+}
+
+func TestAssignResourcesFollowsCapacity(t *testing.T) {
+	timeline := testTimeline()
+	capacity := SlotCapacity{Base: 1, Periods: []CapacityPeriod{{FromDateId: 20250113, Slots: 2}}}
+	slots := NewScheduledSlots(timeline, capacity, 0)
+	slots.AssignResources([]*Resource{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 0)
+
+	if slots.Len() != 3 {
+		t.Fatalf("слотов %d, ожидается 3", slots.Len())
+	}
+	for i := 0; i < slots.Len(); i++ {
+		if slots.items[i].Capacity == nil {
+			t.Errorf("слот %d без расписания", i)
+		}
+	}
+	if _, _, err := slots.place(2, 0, 8*time.Hour, 1); err == nil {
+		t.Error("слот ресурса сверх расписания не должен открываться")
+	}
+	if c := slots.Clone(); c.capacity != slots.capacity {
+		t.Error("копия слотов должна сохранять расписание")
+	}
+}
+
+func TestFindSlotSkipsClosed(t *testing.T) {
+	timeline := testTimeline()
+	capacity := SlotCapacity{Base: 1, Periods: []CapacityPeriod{{FromDateId: 20250113, Slots: 2}}}
+	slots := NewScheduledSlots(timeline, capacity, 0)
+	slots.SetDelay(0, 24*time.Hour)
+
+	if slot, _ := slots.FindSlot(20250106); slot != 0 {
+		t.Errorf("до открытия второго слота выбран слот %d, ожидается 0", slot)
+	}
+	if slot, _ := slots.FindSlot(20250113); slot != 1 {
+		t.Errorf("после открытия второго слота выбран слот %d, ожидается 1", slot)
+	}
+	if _, err := NewSlots(timeline, 0, 0).FindSlot(20250106); err == nil {
+		t.Error("без слотов ожидается ошибка")
+	}
+}
+
+func TestAllocatePrefersSameContextOnTie(t *testing.T) {
+	s := NewSlots(testTimeline(), 2, 0)
+	s.SetContext(0, "A")
+	s.SetContext(1, "B")
+
+	_, _, slots, err := s.Allocate(Demand{Duration: 8 * time.Hour, Context: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slots[0] != 1 {
+		t.Errorf("задача проекта B поставлена в слот %d, ожидается слот того же проекта 1", slots[0])
+	}
+}
+
+func TestAllocateWideDemandPaysSwitchCost(t *testing.T) {
+	s := NewSlots(testTimeline(), 2, 0)
+	s.SwitchCost = 4 * time.Hour
+	s.SetContext(0, "A")
+	s.SetContext(1, "A")
+
+	start, _, _, err := s.Allocate(Demand{Duration: 8 * time.Hour, Width: 2, Context: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 4*time.Hour {
+		t.Errorf("задача на два слота начинается %s, ожидается после переключения 4h", start)
+	}
+}
+
+func TestAddToSlotTakesWidth(t *testing.T) {
+	s := NewSlots(testTimeline(), 3, 0)
+
+	if _, _, err := s.AddToSlot(2, Demand{Duration: 8 * time.Hour, Width: 2}); err != nil {
+		t.Fatal(err)
+	}
+	start, _, _, err := s.Allocate(Demand{Duration: 8 * time.Hour, Width: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 8*time.Hour {
+		t.Errorf("следующая задача на два слота начинается %s, ожидается 8h: задача группы заняла два слота", start)
+	}
+}
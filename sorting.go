@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ключи сортировки, не являющиеся полями Jira
+const (
+	SortKeySource       = "source"            // исходный порядок: JQL или структура
+	SortKeyForest       = "forest"            // позиция строки в структуре
+	SortKeyDeadline     = "deadline"          // срок задачи из настроек deadlines
+	SortKeyManualStart  = "gantt.manualStart" // ручная дата начала в Gantt
+	SortKeyManualFinish = "gantt.manualFinish"
+	SortKeyStart        = "gantt.start"
+	SortKeyFinish       = "gantt.finish"
+	SortKeyDuration     = "gantt.duration"
+)
+
+// sortValue — значение ключа сортировки задачи
+type sortValue struct {
+	missing bool
+	isNum   bool
+	num     float64
+	str     string
+}
+
+func (a sortValue) compare(b sortValue) int {
+	if a.isNum && b.isNum {
+		switch {
+		case a.num < b.num:
+			return -1
+		case a.num > b.num:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a.str, b.str)
+}
+
+// sortFields возвращает поля Jira, которые нужно запросить для сортировки
+func sortFields(keys []SortKeyConfig) []string {
+	var fields []string
+	for _, k := range keys {
+		if !isInternalSortKey(k.Key) {
+			fields = append(fields, k.Key)
+		}
+	}
+	return fields
+}
+
+func isInternalSortKey(key string) bool {
+	return key == SortKeySource || key == SortKeyForest || key == SortKeyDeadline || strings.HasPrefix(key, "gantt.")
+}
+
+func validateSortKeys(keys []SortKeyConfig) error {
+	for _, k := range keys {
+		if k.Key == "" {
+			return fmt.Errorf("не указан key для ключа сортировки")
+		}
+		if k.Order != "" && k.Order != "asc" && k.Order != "desc" {
+			return fmt.Errorf("ключ сортировки '%s': неизвестное направление '%s'", k.Key, k.Order)
+		}
+		if k.Missing != "" && k.Missing != "first" && k.Missing != "last" {
+			return fmt.Errorf("ключ сортировки '%s': неизвестное значение missing '%s'", k.Key, k.Missing)
+		}
+		if strings.HasPrefix(k.Key, "gantt.") {
+			switch k.Key {
+			case SortKeyManualStart, SortKeyManualFinish, SortKeyStart, SortKeyFinish, SortKeyDuration:
+			default:
+				return fmt.Errorf("неизвестный атрибут Gantt для сортировки: '%s'", k.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// sortTasks упорядочивает задачи по ключам сортировки. Каждый следующий ключ разрешает равенство предыдущих,
+// при полном равенстве сохраняется исходный порядок.
+func sortTasks(tasks []*LevelingTask, keys []SortKeyConfig) {
+	if len(keys) == 0 {
+		return
+	}
+
+	values := make(map[*LevelingTask][]sortValue, len(tasks))
+	for _, task := range tasks {
+		row := make([]sortValue, len(keys))
+		for i, k := range keys {
+			row[i] = taskSortValue(task, k)
+		}
+		values[task] = row
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := values[tasks[i]], values[tasks[j]]
+		for n, k := range keys {
+			va, vb := a[n], b[n]
+			if va.missing || vb.missing {
+				if va.missing == vb.missing {
+					continue
+				}
+				// Задачи без значения по умолчанию идут в конце
+				return vb.missing != (k.Missing == "first")
+			}
+			c := va.compare(vb)
+			if c == 0 {
+				continue
+			}
+			if k.Order == "desc" {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func taskSortValue(task *LevelingTask, k SortKeyConfig) sortValue {
+	attributes := task.Attributes
+	switch k.Key {
+	case SortKeySource:
+		return sortValue{isNum: true, num: float64(task.Position)}
+	case SortKeyForest:
+		return sortValue{isNum: true, num: float64(task.Row.Index)}
+	case SortKeyDeadline:
+		return timeSortValue(task.Deadline)
+	case SortKeyManualStart:
+		return timeSortValue(attributes.ManualStart)
+	case SortKeyManualFinish:
+		return timeSortValue(attributes.ManualFinish)
+	case SortKeyStart:
+		return timeSortValue(attributes.Start)
+	case SortKeyFinish:
+		return timeSortValue(attributes.Finish)
+	case SortKeyDuration:
+		return sortValue{isNum: true, num: float64(attributes.Duration)}
+	}
+
+	v := fieldSortValue(k.Key, task.Issue.Fields[k.Key])
+	if len(k.Values) > 0 && !v.missing {
+		// Явный порядок значений, значения не из списка идут после перечисленных
+		for i, name := range k.Values {
+			if strings.EqualFold(name, v.str) {
+				return sortValue{isNum: true, num: float64(i)}
+			}
+		}
+		return sortValue{isNum: true, num: float64(len(k.Values))}
+	}
+	return v
+}
+
+func timeSortValue(t time.Time) sortValue {
+	if t.IsZero() {
+		return sortValue{missing: true}
+	}
+	return sortValue{isNum: true, num: float64(t.Unix())}
+}
+
+// fieldSortValue приводит значение поля Jira к сравнимому виду.
+// Числа сравниваются как числа, строки (в том числе даты и rank) — лексикографически,
+// у объектов берется value или name, у приоритета — id, у массивов — первый элемент.
+func fieldSortValue(field string, raw json.RawMessage) sortValue {
+	if len(raw) == 0 {
+		return sortValue{missing: true}
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return sortValue{missing: true}
+	}
+
+	if arr, ok := v.([]interface{}); ok {
+		if len(arr) == 0 {
+			return sortValue{missing: true}
+		}
+		v = arr[0]
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		switch {
+		case field == "priority" && obj["id"] != nil:
+			v = obj["id"]
+			if s, ok := v.(string); ok {
+				if n, err := strconv.ParseFloat(s, 64); err == nil {
+					// Имя нужно для явного порядка значений
+					if name, ok := obj["name"].(string); ok {
+						return sortValue{isNum: true, num: n, str: name}
+					}
+					return sortValue{isNum: true, num: n, str: s}
+				}
+			}
+		case obj["value"] != nil:
+			v = obj["value"]
+		case obj["name"] != nil:
+			v = obj["name"]
+		default:
+			v = obj["id"]
+		}
+	}
+
+	switch val := v.(type) {
+	case nil:
+		return sortValue{missing: true}
+	case float64:
+		return sortValue{isNum: true, num: val, str: strconv.FormatFloat(val, 'f', -1, 64)}
+	case bool:
+		if val {
+			return sortValue{isNum: true, num: 1, str: "true"}
+		}
+		return sortValue{isNum: true, num: 0, str: "false"}
+	case string:
+		if val == "" {
+			return sortValue{missing: true}
+		}
+		return sortValue{str: val}
+	default:
+		return sortValue{missing: true}
+	}
+}
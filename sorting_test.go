@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFieldSortValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		raw   string
+		want  sortValue
+	}{
+		{name: "нет поля", field: "duedate", want: sortValue{missing: true}},
+		{name: "null", field: "duedate", raw: `null`, want: sortValue{missing: true}},
+		{name: "пустая строка", field: "duedate", raw: `""`, want: sortValue{missing: true}},
+		{name: "строка", field: "duedate", raw: `"2025-01-10"`, want: sortValue{str: "2025-01-10"}},
+		{name: "число", field: "customfield_1", raw: `3.5`, want: sortValue{isNum: true, num: 3.5, str: "3.5"}},
+		{name: "приоритет по id", field: "priority", raw: `{"id":"2","name":"High"}`, want: sortValue{isNum: true, num: 2, str: "High"}},
+		{name: "значение списка", field: "customfield_2", raw: `{"value":"Red","id":"10"}`, want: sortValue{str: "Red"}},
+		{name: "первый элемент массива", field: "components", raw: `[{"name":"api"},{"name":"web"}]`, want: sortValue{str: "api"}},
+		{name: "пустой массив", field: "components", raw: `[]`, want: sortValue{missing: true}},
+		{name: "некорректный JSON", field: "duedate", raw: `{`, want: sortValue{missing: true}},
+	}
+	for _, tt := range tests {
+		var raw json.RawMessage
+		if tt.raw != "" {
+			raw = json.RawMessage(tt.raw)
+		}
+		if got := fieldSortValue(tt.field, raw); got != tt.want {
+			t.Errorf("%s: %+v, ожидается %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	tasks := []*LevelingTask{
+		{Issue: testIssue(t, "T-1", `{"priority":{"id":"3","name":"Low"}}`), Position: 0},
+		{Issue: testIssue(t, "T-2", `{}`), Position: 1},
+		{Issue: testIssue(t, "T-3", `{"priority":{"id":"1","name":"Highest"}}`), Position: 2},
+		{Issue: testIssue(t, "T-4", `{"priority":{"id":"3","name":"Low"}}`), Position: 3},
+	}
+	tests := []struct {
+		name string
+		keys []SortKeyConfig
+		want []string
+	}{
+		{
+			name: "по возрастанию, без значения в конце, равные в исходном порядке",
+			keys: []SortKeyConfig{{Key: "priority"}},
+			want: []string{"T-3", "T-1", "T-4", "T-2"},
+		},
+		{
+			name: "по убыванию, без значения в начале",
+			keys: []SortKeyConfig{{Key: "priority", Order: "desc", Missing: "first"}},
+			want: []string{"T-2", "T-1", "T-4", "T-3"},
+		},
+		{
+			name: "второй ключ разрешает равенство",
+			keys: []SortKeyConfig{{Key: "priority"}, {Key: SortKeySource, Order: "desc"}},
+			want: []string{"T-3", "T-4", "T-1", "T-2"},
+		},
+		{
+			name: "явный порядок значений",
+			keys: []SortKeyConfig{{Key: "priority", Values: []string{"low"}}},
+			want: []string{"T-1", "T-4", "T-3", "T-2"},
+		},
+	}
+	for _, tt := range tests {
+		sorted := append([]*LevelingTask(nil), tasks...)
+		sortTasks(sorted, tt.keys)
+		for i, task := range sorted {
+			if task.Issue.Key != tt.want[i] {
+				t.Errorf("%s: позиция %d — %s, ожидается %s", tt.name, i, task.Issue.Key, tt.want[i])
+			}
+		}
+	}
+}
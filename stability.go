@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// parseStabilityConfig возвращает наибольший допустимый сдвиг начала задачи за запуск, 0 — без ограничения
+func parseStabilityConfig(cfg StabilityConfig) (time.Duration, error) {
+	if cfg.FreezeDays < 0 {
+		return 0, fmt.Errorf("отрицательный freeze_days")
+	}
+	if cfg.MaxShift == "" {
+		return 0, nil
+	}
+	d, err := parseGanttDuration(cfg.MaxShift)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный max_shift: %w", err)
+	}
+	return d, nil
+}
+
+// resolveStability закрепляет задачи, которые по текущему плану начинаются до конца ближайших freeze_days рабочих дней,
+// в том числе задачи с началом в прошлом, и выставляет остальным задачам наибольший допустимый сдвиг.
+// Задачи в работе не закрепляются: они продолжаются с даты начала выравнивания.
+func resolveStability(tasks []*LevelingTask, cfg StabilityConfig, maxShift time.Duration, timeline Timeline, startDateId int) {
+	if cfg.FreezeDays > 0 {
+		freezeEnd := workingDaysAfter(timeline.Calendar, startDateId, cfg.FreezeDays)
+		var frozen int
+		for _, task := range tasks {
+			if task.Summary || task.Pinned || task.InProgress || task.Attributes == nil || task.Attributes.Start.IsZero() {
+				continue
+			}
+			start := dateIdFromTime(task.Attributes.Start)
+			if start < freezeEnd {
+				task.Pinned = true
+				frozen++
+				log.Printf("Задача %s начинается %d, в пределах заморозки плана, и будет закреплена\n", task.Issue.Key, start)
+			}
+		}
+		log.Printf("Заморозка плана до %d: закреплено задач %d\n", freezeEnd, frozen)
+	}
+
+	if maxShift > 0 {
+		for _, task := range tasks {
+			task.MaxShift = maxShift
+		}
+	}
+}
+
+// workingDaysAfter возвращает дату, следующую за days рабочими днями начиная с dateId
+func workingDaysAfter(cal *Calendar, dateId, days int) int {
+	d, err := parseDateId(dateId)
+	if err != nil {
+		return dateId
+	}
+	for i := 0; days > 0 && i < maxTimelineDays; i, d = i+1, d.AddDate(0, 0, 1) {
+		if cal.GetWorkingDurationForDate(dateIdFromTime(d)) > 0 {
+			days--
+		}
+	}
+	return dateIdFromTime(d)
+}
+
+// currentStart возвращает смещение текущего начала задачи в Gantt
+func currentStart(task *LevelingTask, timeline Timeline) (time.Duration, bool) {
+	if task.Attributes == nil || task.Attributes.Start.IsZero() {
+		return 0, false
+	}
+	return timeline.OffsetForDate(dateIdFromTime(task.Attributes.Start)), true
+}
+
+// latestStart возвращает наибольшее допустимое начало задачи: текущее начало плюс max_shift,
+// но не раньше даты начала выравнивания
+func latestStart(task *LevelingTask, timeline Timeline, levelingStart time.Duration) (time.Duration, bool) {
+	if task.MaxShift <= 0 {
+		return 0, false
+	}
+	current, ok := currentStart(task, timeline)
+	if !ok {
+		return 0, false
+	}
+	return max(current+task.MaxShift, levelingStart), true
+}
+
+// reportShiftLimits выводит задачи, которые по расчету должны были сдвинуться позже больше чем на max_shift
+// и поставлены на наибольший допустимый сдвиг
+func reportShiftLimits(tasks []*LevelingTask) {
+	var limited int
+	for _, task := range tasks {
+		if !task.ShiftLimited || task.Horizon != "" || task.PlacementErr != nil {
+			continue
+		}
+		limited++
+		log.Printf("[WARNING] Задача %s по расчету сдвигается больше чем на %s и поставлена с наибольшим допустимым сдвигом\n", task.Issue.Key, task.MaxShift)
+	}
+	if limited > 0 {
+		log.Printf("Задач со сдвигом больше допустимого: %d\n", limited)
+	}
+}
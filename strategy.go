@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StrategyGreedy ставит задачи по порядку в слот, где они начнутся раньше всего
+const StrategyGreedy = "greedy"
+
+// LevelingInput — данные для стратегии выравнивания
+type LevelingInput struct {
+	Tasks    []*LevelingTask // задачи в порядке приоритета с длительностью, сроками и ограничениями
+	Slots    *Slots          // слоты с начальной задержкой и закрепленными ресурсами
+	Timeline Timeline        // календарь и дата начала диаграммы
+}
+
+// LevelingStrategy рассчитывает для задач задержку выравнивания, окончание и слот
+// (поля LevelingDelay, Finish и Slot) и возвращает задачи в итоговом порядке.
+// Закрепленные задачи не записываются в Gantt, но занимают слоты. Строки-родители не занимают слоты,
+// их задержка сбрасывается в 0.
+type LevelingStrategy interface {
+	Level(input LevelingInput) ([]*LevelingTask, error)
+}
+
+// StrategyFactory создает стратегию по настройкам структуры и проверяет их
+type StrategyFactory func(structure StructureConfig) (LevelingStrategy, error)
+
+var strategies = make(map[string]StrategyFactory)
+
+// RegisterStrategy добавляет стратегию, которую можно выбрать параметром strategy структуры
+func RegisterStrategy(name string, factory StrategyFactory) {
+	if _, ok := strategies[name]; ok {
+		panic(fmt.Sprintf("стратегия выравнивания '%s' уже зарегистрирована", name))
+	}
+	strategies[name] = factory
+}
+
+func newStrategy(structure StructureConfig) (LevelingStrategy, error) {
+	name := structure.Strategy
+	if name == "" {
+		name = StrategyGreedy
+	}
+	factory, ok := strategies[name]
+	if !ok {
+		names := make([]string, 0, len(strategies))
+		for n := range strategies {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("неизвестная стратегия выравнивания: '%s', доступны: %s", name, strings.Join(names, ", "))
+	}
+	return factory(structure)
+}
+
+func init() {
+	RegisterStrategy(StrategyGreedy, func(StructureConfig) (LevelingStrategy, error) {
+		return greedyStrategy{}, nil
+	})
+}
+
+type greedyStrategy struct{}
+
+func (greedyStrategy) Level(input LevelingInput) ([]*LevelingTask, error) {
+	scheduleTasks(input.Tasks, input.Slots, input.Timeline)
+	return input.Tasks, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// maxTimelineDays ограничивает поиск по календарю, чтобы календарь без рабочих дней не зациклил расчет
+const maxTimelineDays = 366 * 30
+
+var errTimelineExhausted = errors.New("не найдено рабочего времени в пределах горизонта календаря")
+
+// Timeline переводит смещения в рабочем времени от даты начала диаграммы в даты и обратно
+type Timeline struct {
+	Calendar    *Calendar
+	StartDateId int
+}
+
+// OffsetForDate возвращает рабочее время от начала диаграммы до начала указанного дня.
+// Для дат раньше начала диаграммы смещение отрицательное.
+func (t Timeline) OffsetForDate(dateId int) time.Duration {
+	if dateId < t.StartDateId {
+		return -t.Calendar.GetWorkingDurationBetween(dateId, t.StartDateId)
+	}
+	return t.Calendar.GetWorkingDurationBetween(t.StartDateId, dateId)
+}
+
+// DateForOffset возвращает день, на который приходится смещение, и рабочее время, прошедшее в этом дне.
+// Смещение, попадающее ровно на конец рабочего дня, относится к следующему рабочему дню.
+func (t Timeline) DateForOffset(offset time.Duration) (int, time.Duration, error) {
+	start, err := parseDateId(t.StartDateId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var passed time.Duration
+	d := start
+	for i := 0; i < maxTimelineDays; i, d = i+1, d.AddDate(0, 0, 1) {
+		dateId := dateIdFromTime(d)
+		day := t.Calendar.GetWorkingDurationForDate(dateId)
+		if day > 0 && offset < passed+day {
+			if offset < passed {
+				return dateId, 0, nil
+			}
+			return dateId, offset - passed, nil
+		}
+		passed += day
+	}
+	return 0, 0, errTimelineExhausted
+}
+
+// FinishDateForOffset возвращает день, в котором завершается работа, заканчивающаяся на смещении offset.
+// В отличие от DateForOffset, конец рабочего дня относится к этому же дню.
+func (t Timeline) FinishDateForOffset(offset time.Duration) (int, error) {
+	if offset <= 0 {
+		return t.StartDateId, nil
+	}
+	dateId, into, err := t.DateForOffset(offset)
+	if err != nil {
+		return 0, err
+	}
+	if into > 0 {
+		return dateId, nil
+	}
+
+	// Работа закончилась в конце предыдущего рабочего дня
+	d, err := parseDateId(dateId)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < maxTimelineDays; i++ {
+		d = d.AddDate(0, 0, -1)
+		if t.Calendar.GetWorkingDurationForDate(dateIdFromTime(d)) > 0 {
+			return dateIdFromTime(d), nil
+		}
+	}
+	return 0, errTimelineExhausted
+}
+
+// ConvertOffset переводит смещение в смещение другой диаграммы, сохраняя день и прошедшее в нем рабочее время.
+// Рабочее время дня ограничивается календарем другой диаграммы.
+func (t Timeline) ConvertOffset(offset time.Duration, to Timeline) (time.Duration, error) {
+	if t.Calendar == to.Calendar && t.StartDateId == to.StartDateId {
+		return offset, nil
+	}
+	if offset < 0 {
+		return to.OffsetForDate(t.StartDateId) + offset, nil
+	}
+	dateId, into, err := t.DateForOffset(offset)
+	if err != nil {
+		return 0, err
+	}
+	return to.OffsetForDate(dateId) + min(into, to.Calendar.GetWorkingDurationForDate(dateId)), nil
+}
+
+// ScheduleWork размещает работу длительностью d не раньше смещения from, если в каждый день доступно
+// available(dateId) рабочего времени из рабочего времени календаря. Возвращает смещения начала и окончания:
+// в дни без доступного времени время идет, а работа не выполняется, при неполном дне работа растягивается.
+// Если available сообщает, что начиная с этого дня времени больше не будет, работа не размещается.
+func (t Timeline) ScheduleWork(from, d time.Duration, available func(dateId int) (time.Duration, bool)) (time.Duration, time.Duration, error) {
+	if d <= 0 {
+		return from, from, nil
+	}
+
+	day, err := parseDateId(t.StartDateId)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var passed, start time.Duration
+	started := false
+	left := d
+	for i := 0; i < maxTimelineDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		dateId := dateIdFromTime(day)
+		total := t.Calendar.GetWorkingDurationForDate(dateId)
+		if total <= 0 || passed+total <= from {
+			passed += total
+			continue
+		}
+		free, more := available(dateId)
+		if !more {
+			break
+		}
+		free = min(free, total)
+		if free <= 0 {
+			passed += total
+			continue
+		}
+
+		var into time.Duration
+		if !started {
+			if from > passed {
+				into = from - passed
+			}
+			start = passed + into
+			started = true
+		}
+
+		capacity := scaleDuration(total-into, free, total)
+		if left <= capacity {
+			return start, passed + into + scaleDuration(left, total, free), nil
+		}
+		left -= capacity
+		passed += total
+	}
+	return 0, 0, errTimelineExhausted
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WIPComponent — ограничение по компонентам задачи: задача с несколькими компонентами учитывается в каждом
+const WIPComponent = "component"
+
+// WIPLimit ограничивает количество одновременно выполняемых задач с одинаковым значением ключа
+type WIPLimit struct {
+	Key   string
+	Limit int
+}
+
+// WIPTracker хранит интервалы выполнения задач по ключам ограничений. Слоты всех пулов структуры
+// используют общий WIPTracker, поэтому ограничения действуют между пулами.
+type WIPTracker struct {
+	running map[string][]wipInterval
+}
+
+type wipInterval struct {
+	start, finish time.Duration
+}
+
+func NewWIPTracker() *WIPTracker {
+	return &WIPTracker{running: make(map[string][]wipInterval)}
+}
+
+// Clone возвращает копию, изменения которой не затрагивают исходные интервалы
+func (w *WIPTracker) Clone() *WIPTracker {
+	if w == nil {
+		return nil
+	}
+	c := NewWIPTracker()
+	for key, intervals := range w.running {
+		c.running[key] = append([]wipInterval(nil), intervals...)
+	}
+	return c
+}
+
+// restore заменяет интервалы интервалами from
+func (w *WIPTracker) restore(from *WIPTracker) {
+	w.running = from.Clone().running
+	if w.running == nil {
+		w.running = make(map[string][]wipInterval)
+	}
+}
+
+// blocked проверяет, можно ли выполнять задачу в интервале [start, finish): задача не добавляется, если в какой-то
+// момент интервала уже выполняется l.Limit задач с тем же ключом. Если хотя бы одно ограничение превышено,
+// возвращает смещение, раньше которого задачу начинать бессмысленно.
+func (w *WIPTracker) blocked(limits []WIPLimit, start, finish time.Duration) (time.Duration, bool) {
+	if w == nil {
+		return 0, false
+	}
+	var next time.Duration
+	blocked := false
+	for _, l := range limits {
+		if free, ok := w.freeAfter(l, start, finish); ok {
+			next = max(next, free)
+			blocked = true
+		}
+	}
+	return next, blocked
+}
+
+// freeAfter находит первый момент интервала [start, finish), когда выполняется l.Limit задач, и возвращает
+// смещение, с которого их снова меньше l.Limit. Любое более раннее начало задачи этот момент захватывает.
+func (w *WIPTracker) freeAfter(l WIPLimit, start, finish time.Duration) (time.Duration, bool) {
+	intervals := w.running[l.Key]
+	runningAt := func(t time.Duration) int {
+		var n int
+		for _, iv := range intervals {
+			if iv.start <= t && t < iv.finish {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Количество задач растет только в начале интервалов
+	points := []time.Duration{start}
+	for _, iv := range intervals {
+		if iv.start > start && iv.start < finish {
+			points = append(points, iv.start)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	full, found := time.Duration(0), false
+	for _, t := range points {
+		if runningAt(t) >= l.Limit {
+			full, found = t, true
+			break
+		}
+	}
+	if !found {
+		return 0, false
+	}
+
+	// и уменьшается только в конце интервалов
+	var ends []time.Duration
+	for _, iv := range intervals {
+		if iv.finish > full {
+			ends = append(ends, iv.finish)
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i] < ends[j] })
+	for _, t := range ends {
+		if runningAt(t) < l.Limit {
+			return t, true
+		}
+	}
+	return ends[len(ends)-1], true
+}
+
+// Add учитывает задачу, выполняемую в интервале [start, finish)
+func (w *WIPTracker) Add(limits []WIPLimit, start, finish time.Duration) {
+	if w == nil || finish <= start {
+		return
+	}
+	for _, l := range limits {
+		w.running[l.Key] = append(w.running[l.Key], wipInterval{start: start, finish: finish})
+	}
+}
+
+func validateWIPLimits(limits []WIPLimitConfig) error {
+	for _, l := range limits {
+		if l.Key == "" {
+			return fmt.Errorf("не указан key ограничения одновременно выполняемых задач")
+		}
+		if l.Limit < 0 {
+			return fmt.Errorf("ограничение по '%s': отрицательный limit", l.Key)
+		}
+		values := make(map[string]string, len(l.Values))
+		for value, n := range l.Values {
+			if n < 1 {
+				return fmt.Errorf("ограничение по '%s': limit для '%s' должен быть больше 0", l.Key, value)
+			}
+			if other, ok := values[strings.ToLower(value)]; ok {
+				return fmt.Errorf("ограничение по '%s': значения '%s' и '%s' отличаются только регистром", l.Key, other, value)
+			}
+			values[strings.ToLower(value)] = value
+		}
+	}
+	return nil
+}
+
+func isWIPFieldKey(key string) bool {
+	return key != WIPComponent && isAffinityFieldKey(key)
+}
+
+// resolveWIPLimits выставляет задачам ограничения одновременно выполняемых задач
+func resolveWIPLimits(tasks []*LevelingTask, limits []WIPLimitConfig) {
+	if len(limits) == 0 {
+		return
+	}
+	var count int
+	for _, task := range tasks {
+		for i, l := range limits {
+			for _, value := range wipValues(task, l.Key) {
+				limit := l.Limit
+				for v, n := range l.Values {
+					if strings.EqualFold(v, value) {
+						limit = n
+						break
+					}
+				}
+				if limit > 0 {
+					task.Limits = append(task.Limits, WIPLimit{Key: fmt.Sprintf("%d:%s", i, strings.ToLower(value)), Limit: limit})
+				}
+			}
+		}
+		if len(task.Limits) > 0 {
+			count++
+		}
+	}
+	log.Printf("Задач с ограничением одновременного выполнения: %d\n", count)
+}
+
+// wipValues возвращает значения ключа ограничения для задачи: проект, строку-родителя, компоненты или значение поля
+func wipValues(task *LevelingTask, key string) []string {
+	if key == WIPComponent {
+		return task.Issue.Components()
+	}
+	if v := taskContext(task, key); v != "" {
+		return []string{v}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWIPTrackerBlocked(t *testing.T) {
+	h := time.Hour
+	tests := []struct {
+		name     string
+		running  [][2]time.Duration
+		limit    int
+		start    time.Duration
+		finish   time.Duration
+		blocked  bool
+		freeFrom time.Duration
+	}{
+		{name: "пусто", limit: 1, start: 0, finish: 10 * h},
+		{name: "последовательные задачи не превышают ограничение", running: [][2]time.Duration{{0, 10 * h}, {10 * h, 20 * h}}, limit: 2, start: 5 * h, finish: 15 * h},
+		{name: "одновременные задачи", running: [][2]time.Duration{{0, 10 * h}, {5 * h, 20 * h}}, limit: 2, start: 8 * h, finish: 12 * h, blocked: true, freeFrom: 10 * h},
+		{name: "ограничение достигается внутри интервала", running: [][2]time.Duration{{0, 10 * h}, {6 * h, 12 * h}}, limit: 2, start: 2 * h, finish: 8 * h, blocked: true, freeFrom: 10 * h},
+		{name: "ограничение 1", running: [][2]time.Duration{{0, 10 * h}}, limit: 1, start: 0, finish: 5 * h, blocked: true, freeFrom: 10 * h},
+		{name: "задача после окончания", running: [][2]time.Duration{{0, 10 * h}}, limit: 1, start: 10 * h, finish: 15 * h},
+		{name: "освобождение после цепочки", running: [][2]time.Duration{{0, 10 * h}, {0, 10 * h}, {10 * h, 20 * h}, {10 * h, 14 * h}}, limit: 2, start: 5 * h, finish: 8 * h, blocked: true, freeFrom: 14 * h},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWIPTracker()
+			limits := []WIPLimit{{Key: "0:epic-1", Limit: tt.limit}}
+			for _, r := range tt.running {
+				w.Add(limits, r[0], r[1])
+			}
+			next, blocked := w.blocked(limits, tt.start, tt.finish)
+			if blocked != tt.blocked {
+				t.Fatalf("blocked = %v, ожидается %v", blocked, tt.blocked)
+			}
+			if blocked && next != tt.freeFrom {
+				t.Errorf("next = %s, ожидается %s", next, tt.freeFrom)
+			}
+		})
+	}
+}
+
+func TestWIPTrackerNil(t *testing.T) {
+	var w *WIPTracker
+	if _, blocked := w.blocked([]WIPLimit{{Key: "k", Limit: 1}}, 0, time.Hour); blocked {
+		t.Error("без ограничений задача не должна блокироваться")
+	}
+}
+
+func TestValidateWIPLimitsCaseDuplicates(t *testing.T) {
+	err := validateWIPLimits([]WIPLimitConfig{{Key: "project", Limit: 1, Values: map[string]int{"ABC": 1, "abc": 2}}})
+	if err == nil {
+		t.Error("значения, отличающиеся регистром, должны отклоняться")
+	}
+}